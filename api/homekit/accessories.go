@@ -0,0 +1,316 @@
+package main
+
+import (
+	"hash/fnv"
+
+	"github.com/brutella/hc/accessory"
+	"github.com/brutella/hc/characteristic"
+	"github.com/brutella/hc/service"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+	"go-home.io/x/server/plugins/helpers"
+)
+
+const (
+	// Describes HAP ID reserved for the bridge accessory.
+	bridgeAccessoryID = 1
+	// Describes battery level which is considered as low.
+	lowBatteryLevel = 20
+)
+
+// HAP accessory which represents a single go-home device.
+// Services are selected based on the first received device state.
+type deviceAccessory struct {
+	deviceID   string
+	deviceType enums.DeviceType
+	accessory  *accessory.Accessory
+
+	lightbulb   *service.Lightbulb
+	onOff       *service.Switch
+	lock        *service.LockMechanism
+	temperature *service.TemperatureSensor
+	humidity    *service.HumiditySensor
+	motion      *service.MotionSensor
+	battery     *service.BatteryService
+}
+
+// Constructs a new accessory. Returns nil if device doesn't have
+// anything which could be exposed to HomeKit.
+func (e *HomeKitBridge) newDeviceAccessory(update *DeviceUpdateMessage) *deviceAccessory {
+	info := accessory.Info{
+		Name:         update.Name,
+		SerialNumber: update.DeviceID,
+		Manufacturer: "go-home",
+		Model:        update.DeviceType.String(),
+	}
+
+	d := &deviceAccessory{
+		deviceID:   update.DeviceID,
+		deviceType: update.DeviceType,
+	}
+
+	switch update.DeviceType {
+	case enums.DevLight, enums.DevGroup:
+		d.accessory = accessory.New(info, accessory.TypeLightbulb)
+		d.lightbulb = e.newLightbulb(d, update)
+		d.accessory.AddService(d.lightbulb.Service)
+	case enums.DevSwitch, enums.DevVacuum:
+		d.accessory = accessory.New(info, accessory.TypeSwitch)
+		d.onOff = service.NewSwitch()
+		d.onOff.On.OnValueRemoteUpdate(func(on bool) {
+			e.sendOnOffCommand(d.deviceID, on)
+		})
+		d.accessory.AddService(d.onOff.Service)
+	case enums.DevLock:
+		d.accessory = accessory.New(info, accessory.TypeDoorLock)
+		d.lock = service.NewLockMechanism()
+		d.lock.LockTargetState.OnValueRemoteUpdate(func(value int) {
+			e.sendOnOffCommand(d.deviceID, value != characteristic.LockTargetStateSecured)
+		})
+		d.accessory.AddService(d.lock.Service)
+	case enums.DevSensor:
+		d.accessory = accessory.New(info, accessory.TypeSensor)
+		if !d.addSensorServices(update) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	d.accessory.ID = getAccessoryID(update.DeviceID)
+
+	if _, ok := update.State[enums.PropBatteryLevel]; ok {
+		d.battery = service.NewBatteryService()
+		d.accessory.AddService(d.battery.Service)
+	}
+
+	d.Update(update)
+	return d
+}
+
+// Constructs lightbulb service with characteristics supported by the device.
+// Stock service always exposes brightness and color, so it isn't used.
+func (e *HomeKitBridge) newLightbulb(d *deviceAccessory, update *DeviceUpdateMessage) *service.Lightbulb {
+	l := &service.Lightbulb{
+		Service: service.New(service.TypeLightbulb),
+		On:      characteristic.NewOn(),
+	}
+
+	l.On.OnValueRemoteUpdate(func(on bool) {
+		e.sendOnOffCommand(d.deviceID, on)
+	})
+	l.AddCharacteristic(l.On.Characteristic)
+
+	if _, ok := update.State[enums.PropBrightness]; ok {
+		l.Brightness = characteristic.NewBrightness()
+		l.Brightness.OnValueRemoteUpdate(func(value int) {
+			e.sendCommand(d.deviceID, enums.CmdSetBrightness, &common.Percent{Value: uint8(value)})
+		})
+		l.AddCharacteristic(l.Brightness.Characteristic)
+	}
+
+	if _, ok := update.State[enums.PropColor]; ok {
+		l.Hue = characteristic.NewHue()
+		l.Saturation = characteristic.NewSaturation()
+
+		// HomeKit sends hue and saturation separately,
+		// so color is built from the latest values of both.
+		sendColor := func(float64) {
+			color := hsvToRGB(l.Hue.GetValue(), l.Saturation.GetValue())
+			e.sendCommand(d.deviceID, enums.CmdSetColor, &color)
+		}
+
+		l.Hue.OnValueRemoteUpdate(sendColor)
+		l.Saturation.OnValueRemoteUpdate(sendColor)
+		l.AddCharacteristic(l.Hue.Characteristic)
+		l.AddCharacteristic(l.Saturation.Characteristic)
+	}
+
+	return l
+}
+
+// Adds sensor services based on available properties.
+// Returns false if none of sensor properties are supported.
+func (d *deviceAccessory) addSensorServices(update *DeviceUpdateMessage) bool {
+	if _, ok := update.State[enums.PropTemperature]; ok {
+		d.temperature = service.NewTemperatureSensor()
+		d.accessory.AddService(d.temperature.Service)
+	}
+
+	if _, ok := update.State[enums.PropHumidity]; ok {
+		d.humidity = service.NewHumiditySensor()
+		d.accessory.AddService(d.humidity.Service)
+	}
+
+	if getSensorType(update) == enums.SenMotion {
+		d.motion = service.NewMotionSensor()
+		d.accessory.AddService(d.motion.Service)
+	}
+
+	return d.temperature != nil || d.humidity != nil || d.motion != nil
+}
+
+// Update applies device state received from master.
+func (d *deviceAccessory) Update(update *DeviceUpdateMessage) {
+	isOn := getIsOn(update)
+
+	if d.lightbulb != nil {
+		d.updateLightbulb(update, isOn)
+	}
+
+	if d.onOff != nil {
+		d.onOff.On.SetValue(isOn)
+	}
+
+	if d.lock != nil {
+		state := characteristic.LockCurrentStateUnsecured
+		if !isOn {
+			state = characteristic.LockCurrentStateSecured
+		}
+
+		d.lock.LockCurrentState.SetValue(state)
+		d.lock.LockTargetState.SetValue(state)
+	}
+
+	if d.motion != nil {
+		d.motion.MotionDetected.SetValue(isOn)
+	}
+
+	if d.temperature != nil {
+		if t, ok := getFloat(update.State[enums.PropTemperature]); ok {
+			d.temperature.CurrentTemperature.SetValue(t)
+		}
+	}
+
+	if d.humidity != nil {
+		if h, ok := getFloat(update.State[enums.PropHumidity]); ok {
+			d.humidity.CurrentRelativeHumidity.SetValue(h)
+		}
+	}
+
+	if d.battery != nil {
+		if b, ok := getPercent(update.State[enums.PropBatteryLevel], enums.PropBatteryLevel); ok {
+			d.battery.BatteryLevel.SetValue(int(b))
+			low := characteristic.StatusLowBatteryBatteryLevelNormal
+			if b < lowBatteryLevel {
+				low = characteristic.StatusLowBatteryBatteryLevelLow
+			}
+
+			d.battery.StatusLowBattery.SetValue(low)
+		}
+	}
+}
+
+// Applies light state to the lightbulb service.
+func (d *deviceAccessory) updateLightbulb(update *DeviceUpdateMessage, isOn bool) {
+	d.lightbulb.On.SetValue(isOn)
+
+	if d.lightbulb.Brightness != nil {
+		if b, ok := getPercent(update.State[enums.PropBrightness], enums.PropBrightness); ok {
+			d.lightbulb.Brightness.SetValue(int(b))
+		}
+	}
+
+	if d.lightbulb.Hue != nil {
+		if c, ok := getColor(update.State[enums.PropColor]); ok {
+			h, s := rgbToHS(c)
+			d.lightbulb.Hue.SetValue(h)
+			d.lightbulb.Saturation.SetValue(s)
+		}
+	}
+}
+
+// Returns stable HAP accessory ID, so HomeKit keeps rooms and
+// automations after restart.
+func getAccessoryID(deviceID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(deviceID)) // nolint: gosec, errcheck
+	id := int64(h.Sum64() >> 1)
+	if id <= bridgeAccessoryID {
+		id += bridgeAccessoryID + 1
+	}
+
+	return id
+}
+
+// Transforms device state into ON/OFF status.
+func getIsOn(update *DeviceUpdateMessage) bool {
+	if enums.DevVacuum == update.DeviceType {
+		st, ok := update.State[enums.PropVacStatus]
+		if !ok {
+			return false
+		}
+
+		status, err := helpers.UnmarshalProperty(st, enums.PropVacStatus)
+		if err != nil {
+			return false
+		}
+
+		return status == enums.VacCleaning
+	}
+
+	on, ok := update.State[enums.PropOn].(bool)
+	return ok && on
+}
+
+// Returns sensor type.
+func getSensorType(update *DeviceUpdateMessage) enums.SensorType {
+	st, ok := update.State[enums.PropSensorType]
+	if !ok {
+		return enums.SenGeneric
+	}
+
+	sensorType, err := helpers.UnmarshalProperty(st, enums.PropSensorType)
+	if err != nil {
+		return enums.SenGeneric
+	}
+
+	res, _ := sensorType.(enums.SensorType) // nolint: gosec
+	return res
+}
+
+// Transforms percent property into value.
+func getPercent(value interface{}, prop enums.Property) (uint8, bool) {
+	if nil == value {
+		return 0, false
+	}
+
+	p, err := helpers.UnmarshalProperty(value, prop)
+	if err != nil {
+		return 0, false
+	}
+
+	percent, ok := p.(common.Percent)
+	return percent.Value, ok
+}
+
+// Transforms color property into value.
+func getColor(value interface{}) (common.Color, bool) {
+	if nil == value {
+		return common.Color{}, false
+	}
+
+	c, err := helpers.UnmarshalProperty(value, enums.PropColor)
+	if err != nil {
+		return common.Color{}, false
+	}
+
+	color, ok := c.(common.Color)
+	return color, ok
+}
+
+// Transforms numeric property into float value.
+func getFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case map[string]interface{}:
+		return getFloat(v["value"])
+	}
+
+	return 0, false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+
+	"go-home.io/x/server/plugins/common"
+)
+
+// Converts HomeKit hue (0-360) and saturation (0-100) into RGB color.
+// Brightness is controlled separately, so color is built at full value.
+func hsvToRGB(hue float64, saturation float64) common.Color {
+	s := saturation / 100
+	c := s
+	x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := 1 - c
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = c, x, 0
+	case hue < 120:
+		r, g, b = x, c, 0
+	case hue < 180:
+		r, g, b = 0, c, x
+	case hue < 240:
+		r, g, b = 0, x, c
+	case hue < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return common.Color{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+	}
+}
+
+// Converts RGB color into HomeKit hue (0-360) and saturation (0-100).
+func rgbToHS(color common.Color) (float64, float64) {
+	r := float64(color.R) / 255
+	g := float64(color.G) / 255
+	b := float64(color.B) / 255
+
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	delta := max - min
+	if 0 == delta {
+		return 0, 0
+	}
+
+	var hue float64
+	switch max {
+	case r:
+		hue = 60 * math.Mod((g-b)/delta, 6)
+	case g:
+		hue = 60 * ((b-r)/delta + 2)
+	default:
+		hue = 60 * ((r-g)/delta + 4)
+	}
+
+	if hue < 0 {
+		hue += 360
+	}
+
+	return math.Round(hue), math.Round(delta / max * 100)
+}
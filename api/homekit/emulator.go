@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/brutella/hc"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+)
+
+// HomeKitBridge implements extended API plugin and
+// provides HomeKit accessories bridge.
+type HomeKitBridge struct {
+	sync.Mutex
+
+	Settings *Settings
+
+	logger       common.ILoggerProvider
+	secret       common.ISecretProvider
+	isMaster     bool
+	communicator api.IExtendedAPICommunicator
+
+	devices            map[string]*DeviceUpdateMessage
+	unsupportedDevices map[string]bool
+
+	accessories  map[string]*deviceAccessory
+	transport    hc.Transport
+	restartTimer *time.Timer
+	storage      *pairingStorage
+
+	chCommands chan []byte
+	stopChan   chan bool
+}
+
+// Init starts plugin.
+func (e *HomeKitBridge) Init(data *api.InitDataAPI) error {
+	e.communicator = data.Communicator
+	e.isMaster = data.IsMaster
+	e.logger = data.Logger
+	e.secret = data.Secret
+
+	if data.IsMaster {
+		return e.initMaster(data)
+	}
+
+	return e.initWorker(data)
+}
+
+// Routes returns nothing, since we're not exposing anything
+// user-related.
+func (e *HomeKitBridge) Routes() []string {
+	return []string{}
+}
+
+// Unload stops internal processing cycles.
+func (e *HomeKitBridge) Unload() {
+	close(e.chCommands)
+	close(e.stopChan)
+
+	if !e.isMaster {
+		e.stopTransport()
+	}
+}
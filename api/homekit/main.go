@@ -0,0 +1,20 @@
+// Package main contains HomeKit bridge implementation for the go-home extended API.
+// Worker exposes selected devices as HAP accessories, master tracks devices states
+// and invokes commands.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &HomeKitBridge{
+			Settings:           settings,
+			devices:            make(map[string]*DeviceUpdateMessage),
+			unsupportedDevices: make(map[string]bool),
+			accessories:        make(map[string]*deviceAccessory),
+			chCommands:         make(chan []byte, 5),
+			stopChan:           make(chan bool),
+		},
+		settings, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// Init plugin on master node.
+func (e *HomeKitBridge) initMaster(data *api.InitDataAPI) error {
+	_, chUpdate := data.FanOut.SubscribeDeviceUpdates()
+	err := e.communicator.Subscribe(e.chCommands)
+	if err != nil {
+		return errors.Wrap(err, "subscription failed")
+	}
+	go e.masterCycle(chUpdate, e.chCommands)
+	return nil
+}
+
+// Master internal cycle. Waits for incoming messages
+// either from FanOut or from worker.
+func (e *HomeKitBridge) masterCycle(devUpdates chan *common.MsgDeviceUpdate, devCommands chan []byte) {
+	for {
+		select {
+		case update := <-devUpdates:
+			go e.processIncomingDeviceUpdate(update)
+		case cmd, ok := <-devCommands:
+			if !ok {
+				return
+			}
+			go e.processDeviceCommands(cmd)
+		}
+	}
+}
+
+// Processes messages received from worker.
+// If message is marked as discovery -- it's first load on a worker.
+// We need to send all known devices states.
+func (e *HomeKitBridge) processDeviceCommands(msg []byte) {
+	cmd := &DeviceCommandMessage{}
+	err := json.Unmarshal(msg, cmd)
+	if err != nil {
+		e.logger.Error("Received corrupted message", err)
+		return
+	}
+
+	if cmd.IsDiscovery {
+		e.Lock()
+		devices := make([]*DeviceUpdateMessage, 0, len(e.devices))
+		for _, v := range e.devices {
+			devices = append(devices, copyDevice(v))
+		}
+		e.Unlock()
+
+		for _, v := range devices {
+			e.communicator.Publish(v)
+		}
+
+		return
+	}
+
+	g, err := glob.Compile(glob.QuoteMeta(cmd.DeviceID))
+	if err != nil {
+		e.logger.Error("Failed to compile device regexp", err)
+		return
+	}
+
+	a := make(map[string]interface{})
+	if cmd.Attributes != nil {
+		data, err := json.Marshal(cmd.Attributes)
+		if err != nil {
+			e.logger.Error("Failed to encode command message", err)
+			return
+		}
+
+		err = json.Unmarshal(data, &a)
+		if err != nil {
+			e.logger.Error("Failed to decode command message", err)
+			return
+		}
+	}
+
+	e.logger.Debug("Invoking HomeKit command", common.LogIDToken, cmd.DeviceID,
+		common.LogDeviceCommandToken, cmd.Command.String())
+	e.communicator.InvokeDeviceCommand(g, cmd.Command, a)
+}
+
+// Processes FanOut device updates, converts them to worker msg
+// and send through service bus.
+func (e *HomeKitBridge) processIncomingDeviceUpdate(msg *common.MsgDeviceUpdate) {
+	e.Lock()
+	out := e.updateDevice(msg)
+	e.Unlock()
+
+	if out != nil {
+		e.communicator.Publish(out)
+	}
+}
+
+// Applies device update and returns a copy of the changed device.
+// Returns nil if workers should not be notified.
+// Should be called under bridge lock.
+func (e *HomeKitBridge) updateDevice(msg *common.MsgDeviceUpdate) *DeviceUpdateMessage {
+	if e.unsupportedDevices[msg.ID] {
+		return nil
+	}
+
+	out, ok := e.devices[msg.ID]
+	if !ok {
+		if !e.isMatch(msg) {
+			e.unsupportedDevices[msg.ID] = true
+			return nil
+		}
+
+		out = &DeviceUpdateMessage{
+			DeviceType: msg.Type,
+			Name:       e.getDeviceName(msg),
+			DeviceID:   msg.ID,
+			State:      make(map[enums.Property]interface{}),
+		}
+
+		e.devices[msg.ID] = out
+	}
+
+	wasUpdated := false
+
+	for k, v := range msg.State {
+		if !enums.SliceContainsProperty(supportedProperties, k) {
+			continue
+		}
+
+		out.State[k] = v
+		wasUpdated = true
+	}
+
+	if !wasUpdated {
+		return nil
+	}
+
+	return copyDevice(out)
+}
+
+// Returns a copy of the device which is safe to use without bridge lock.
+func copyDevice(device *DeviceUpdateMessage) *DeviceUpdateMessage {
+	res := *device
+	res.State = make(map[enums.Property]interface{}, len(device.State))
+	for k, v := range device.State {
+		res.State[k] = v
+	}
+
+	return &res
+}
+
+// Validates whether device matches filter regexps and its type is supported.
+func (e *HomeKitBridge) isMatch(msg *common.MsgDeviceUpdate) bool {
+	if !enums.SliceContainsDeviceType(e.Settings.types, msg.Type) {
+		return false
+	}
+
+	for _, v := range e.Settings.devRegexp {
+		if v.Match(msg.ID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns either overwritten name or uses device name.
+func (e *HomeKitBridge) getDeviceName(msg *common.MsgDeviceUpdate) string {
+	name, ok := e.Settings.NamesOverrides[msg.ID]
+	if ok {
+		return name
+	}
+
+	if "" == msg.Name {
+		return msg.ID
+	}
+
+	return msg.Name
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// DeviceUpdateMessage has data about device update.
+// This message is produced by master.
+type DeviceUpdateMessage struct {
+	api.ExtendedAPIMessage
+	Name       string                         `json:"n"`
+	DeviceType enums.DeviceType               `json:"t"`
+	DeviceID   string                         `json:"i"`
+	State      map[enums.Property]interface{} `json:"s"`
+}
+
+// DeviceCommandMessage has data with new device command.
+// This message is produced by worker.
+type DeviceCommandMessage struct {
+	api.ExtendedAPIMessage
+	IsDiscovery bool          `json:"d"`
+	DeviceID    string        `json:"i"`
+	Command     enums.Command `json:"c"`
+	Attributes  interface{}   `json:"a"`
+}
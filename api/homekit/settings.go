@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// Settings has data required to start API.
+type Settings struct {
+	Name           string             `yaml:"name" default:"go-home"`
+	Pin            string             `yaml:"pin" validate:"required"`
+	Port           int                `yaml:"port" validate:"gte=0,lte=65535"`
+	StoragePath    string             `yaml:"storagePath" default:"/tmp/go-home-homekit"`
+	PairingSecret  string             `yaml:"pairingSecret" default:"homekit-pairing"`
+	SettleTimeout  int                `yaml:"settleTimeout" validate:"gt=0" default:"10"`
+	SyncInterval   int                `yaml:"syncInterval" validate:"gt=0" default:"30"`
+	DeviceFilter   []string           `yaml:"devices"`
+	DeviceTypes    []enums.DeviceType `yaml:"types"`
+	NamesOverrides map[string]string  `yaml:"nameOverrides"`
+
+	devRegexp []glob.Glob
+	types     []enums.DeviceType
+}
+
+// HomeKit accepts 8 digits PIN, optionally in XXX-XX-XXX format.
+var pinRegexp = regexp.MustCompile(`^(\d{8}|\d{3}-\d{2}-\d{3})$`)
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	if !pinRegexp.MatchString(s.Pin) {
+		return errors.New("pin should contain 8 digits")
+	}
+
+	s.Pin = strings.Replace(s.Pin, "-", "", -1)
+
+	s.types = make([]enums.DeviceType, 0)
+	for _, v := range s.DeviceTypes {
+		if !enums.SliceContainsDeviceType(supportedTypes, v) {
+			continue
+		}
+
+		s.types = append(s.types, v)
+	}
+
+	if 0 == len(s.types) {
+		s.types = supportedTypes
+	}
+
+	if 0 == len(s.DeviceFilter) {
+		s.DeviceFilter = []string{"**"}
+	}
+
+	s.devRegexp = make([]glob.Glob, 0)
+	for _, v := range s.DeviceFilter {
+		a, err := glob.Compile(v)
+		if err != nil {
+			return errors.Wrap(err, "glob compile failed")
+		}
+
+		s.devRegexp = append(s.devRegexp, a)
+	}
+
+	return nil
+}
+
+// List of supported device types.
+var supportedTypes = []enums.DeviceType{enums.DevLight, enums.DevSwitch, enums.DevGroup,
+	enums.DevLock, enums.DevSensor, enums.DevVacuum}
+
+// List of supported device properties.
+var supportedProperties = []enums.Property{enums.PropOn, enums.PropBrightness, enums.PropTemperature,
+	enums.PropHumidity, enums.PropBatteryLevel, enums.PropVacStatus, enums.PropSensorType}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// HAP keeps pairing data in the local folder. Folder content
+// is mirrored into the secret storage, so iOS devices stay paired
+// after worker is moved to another node.
+type pairingStorage struct {
+	sync.Mutex
+
+	logger     common.ILoggerProvider
+	secret     common.ISecretProvider
+	path       string
+	secretName string
+
+	lastSaved string
+}
+
+// Constructs a new pairing storage.
+func newPairingStorage(logger common.ILoggerProvider, secret common.ISecretProvider,
+	path string, secretName string) *pairingStorage {
+	return &pairingStorage{
+		logger:     logger,
+		secret:     secret,
+		path:       path,
+		secretName: secretName,
+	}
+}
+
+// Restore writes saved pairing data into the local folder.
+func (p *pairingStorage) Restore() error {
+	err := os.MkdirAll(p.path, 0700)
+	if err != nil {
+		return errors.Wrap(err, "storage folder create failed")
+	}
+
+	if nil == p.secret {
+		return nil
+	}
+
+	data, err := p.secret.Get(p.secretName)
+	if err != nil {
+		p.logger.Info("No saved HomeKit pairing found, starting from scratch")
+		return nil
+	}
+
+	files := make(map[string]string)
+	err = json.Unmarshal([]byte(data), &files)
+	if err != nil {
+		p.logger.Error("Saved HomeKit pairing is corrupted, starting from scratch", err)
+		return nil
+	}
+
+	for k, v := range files {
+		content, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			p.logger.Warn("Skipping corrupted HomeKit pairing file", "file", k)
+			continue
+		}
+
+		err = ioutil.WriteFile(filepath.Join(p.path, filepath.Base(k)), content, 0600)
+		if err != nil {
+			return errors.Wrap(err, "storage file write failed")
+		}
+	}
+
+	p.lastSaved = data
+	return nil
+}
+
+// Save puts local folder content into the secret storage, if it was changed.
+func (p *pairingStorage) Save() {
+	if nil == p.secret {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	entries, err := ioutil.ReadDir(p.path)
+	if err != nil {
+		p.logger.Error("Failed to read HomeKit storage folder", err)
+		return
+	}
+
+	files := make(map[string]string)
+	for _, v := range entries {
+		if v.IsDir() {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(p.path, v.Name()))
+		if err != nil {
+			p.logger.Error("Failed to read HomeKit storage file", err, "file", v.Name())
+			return
+		}
+
+		files[v.Name()] = base64.StdEncoding.EncodeToString(content)
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		p.logger.Error("Failed to encode HomeKit pairing", err)
+		return
+	}
+
+	if string(data) == p.lastSaved {
+		return
+	}
+
+	err = p.secret.Set(p.secretName, string(data))
+	if err != nil {
+		p.logger.Error("Failed to save HomeKit pairing", err)
+		return
+	}
+
+	p.lastSaved = string(data)
+	p.logger.Debug("Saved HomeKit pairing")
+}
+
+// Periodically saves pairing data, since HAP doesn't notify about new pairings.
+func (p *pairingStorage) syncCycle(interval time.Duration, stop chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Save()
+		case <-stop:
+			return
+		}
+	}
+}
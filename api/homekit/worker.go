@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/brutella/hc"
+	"github.com/brutella/hc/accessory"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+const (
+	// Describes maximum number of accessories supported by a single HAP bridge,
+	// including bridge itself.
+	maxAccessories = 150
+)
+
+// Init plugin on worker node.
+func (e *HomeKitBridge) initWorker(_ *api.InitDataAPI) error {
+	e.storage = newPairingStorage(e.logger, e.secret, e.Settings.StoragePath, e.Settings.PairingSecret)
+	err := e.storage.Restore()
+	if err != nil {
+		return errors.Wrap(err, "pairing restore failed")
+	}
+
+	err = e.communicator.Subscribe(e.chCommands)
+	if err != nil {
+		return errors.Wrap(err, "bus subscription failed")
+	}
+
+	go e.workerCycle(e.chCommands)
+	go e.storage.syncCycle(time.Duration(e.Settings.SyncInterval)*time.Second, e.stopChan)
+
+	e.Lock()
+	e.scheduleRestart()
+	e.Unlock()
+
+	e.communicator.Publish(&DeviceCommandMessage{
+		IsDiscovery: true,
+	})
+	return nil
+}
+
+// Worker internal bus cycle. Waits for incoming devices updates.
+func (e *HomeKitBridge) workerCycle(devUpdates chan []byte) {
+	for msg := range devUpdates {
+		go e.processDeviceUpdate(msg)
+	}
+}
+
+// Processes incoming device update message.
+// HAP doesn't allow to add accessories on the fly,
+// so transport is restarted once a new device is received.
+func (e *HomeKitBridge) processDeviceUpdate(msg []byte) {
+	e.Lock()
+	defer e.Unlock()
+
+	update := &DeviceUpdateMessage{}
+	err := json.Unmarshal(msg, update)
+	if err != nil {
+		e.logger.Error("Received corrupted message from master", err)
+		return
+	}
+
+	if e.unsupportedDevices[update.DeviceID] {
+		return
+	}
+
+	a, ok := e.accessories[update.DeviceID]
+	if ok {
+		a.Update(update)
+		return
+	}
+
+	a = e.newDeviceAccessory(update)
+	if nil == a {
+		e.logger.Warn("Device can't be exposed to HomeKit", common.LogIDToken, update.DeviceID)
+		e.unsupportedDevices[update.DeviceID] = true
+		return
+	}
+
+	e.accessories[update.DeviceID] = a
+	e.scheduleRestart()
+}
+
+// Delays transport restart, so devices received in
+// a short period of time are applied at once.
+// Should be called under bridge lock.
+func (e *HomeKitBridge) scheduleRestart() {
+	if e.restartTimer != nil {
+		e.restartTimer.Stop()
+	}
+
+	e.restartTimer = time.AfterFunc(time.Duration(e.Settings.SettleTimeout)*time.Second, e.restartTransport)
+}
+
+// Starts HAP transport with all known accessories.
+func (e *HomeKitBridge) restartTransport() {
+	e.Lock()
+	defer e.Unlock()
+
+	select {
+	case <-e.stopChan:
+		return
+	default:
+	}
+
+	e.stopTransportLocked()
+
+	bridge := accessory.NewBridge(accessory.Info{
+		Name:         e.Settings.Name,
+		Manufacturer: "go-home",
+		Model:        "go-home bridge",
+	})
+	bridge.ID = bridgeAccessoryID
+
+	keys := make([]string, 0, len(e.accessories))
+	for k := range e.accessories {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	if len(keys) >= maxAccessories {
+		e.logger.Warn("Too many devices for a single HomeKit bridge, some of them are ignored",
+			"max", strconv.Itoa(maxAccessories-1))
+		keys = keys[:maxAccessories-1]
+	}
+
+	list := make([]*accessory.Accessory, 0, len(keys))
+	for _, v := range keys {
+		list = append(list, e.accessories[v].accessory)
+	}
+
+	config := hc.Config{
+		Pin:         e.Settings.Pin,
+		StoragePath: e.Settings.StoragePath,
+	}
+
+	if e.Settings.Port > 0 {
+		config.Port = strconv.Itoa(e.Settings.Port)
+	}
+
+	t, err := hc.NewIPTransport(config, bridge.Accessory, list...)
+	if err != nil {
+		e.logger.Error("Failed to start HomeKit transport", err)
+		return
+	}
+
+	e.transport = t
+	go t.Start()
+	e.logger.Info("Started HomeKit bridge", "devices", strconv.Itoa(len(list)))
+}
+
+// Stops HAP transport and saves pairing data.
+func (e *HomeKitBridge) stopTransport() {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.restartTimer != nil {
+		e.restartTimer.Stop()
+	}
+
+	e.stopTransportLocked()
+}
+
+// Stops HAP transport. Should be called under bridge lock.
+func (e *HomeKitBridge) stopTransportLocked() {
+	if nil == e.transport {
+		return
+	}
+
+	<-e.transport.Stop()
+	e.transport = nil
+	e.storage.Save()
+}
+
+// Sends on/off command to master.
+func (e *HomeKitBridge) sendOnOffCommand(deviceID string, isOn bool) {
+	cmd := enums.CmdOff
+	if isOn {
+		cmd = enums.CmdOn
+	}
+
+	e.sendCommand(deviceID, cmd, nil)
+}
+
+// Sends device command to master.
+func (e *HomeKitBridge) sendCommand(deviceID string, cmd enums.Command, attributes interface{}) {
+	e.logger.Debug("Received HomeKit command", common.LogIDToken, deviceID,
+		common.LogDeviceCommandToken, cmd.String())
+	e.communicator.Publish(&DeviceCommandMessage{
+		DeviceID:   deviceID,
+		Command:    cmd,
+		Attributes: attributes,
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+
+	"go-home.io/x/server/plugins/common"
+)
+
+// Keeps device to bridge assignments in the secret storage next to HUE IDs,
+// so after restart devices are exposed by the same bridge with the same ID.
+// Bridges are identified by their IDs secret name.
+// Should be used under emulator lock.
+type bridgeAssignments struct {
+	logger     common.ILoggerProvider
+	secret     common.ISecretProvider
+	secretName string
+
+	bridges map[string]string
+}
+
+// Constructs a new assignments storage and loads previously saved assignments.
+func newBridgeAssignments(logger common.ILoggerProvider, secret common.ISecretProvider,
+	secretName string) *bridgeAssignments {
+	a := &bridgeAssignments{
+		logger:     logger,
+		secret:     secret,
+		secretName: secretName,
+		bridges:    make(map[string]string),
+	}
+
+	a.load()
+	return a
+}
+
+// Get returns bridge the device was assigned to.
+func (a *bridgeAssignments) Get(deviceID string) (string, bool) {
+	bridge, ok := a.bridges[deviceID]
+	return bridge, ok
+}
+
+// Set assigns the device to the bridge.
+func (a *bridgeAssignments) Set(deviceID string, bridge string) {
+	if old, ok := a.bridges[deviceID]; ok && old == bridge {
+		return
+	}
+
+	a.bridges[deviceID] = bridge
+	a.save()
+}
+
+// Reserved returns number of devices assigned to the bridge
+// which are not exposed yet.
+func (a *bridgeAssignments) Reserved(bridge string, exposed map[string]*DeviceUpdateMessage) int {
+	reserved := 0
+	for k, v := range a.bridges {
+		if _, ok := exposed[k]; !ok && v == bridge {
+			reserved++
+		}
+	}
+
+	return reserved
+}
+
+// Loads saved assignments from the secret storage.
+func (a *bridgeAssignments) load() {
+	if nil == a.secret {
+		return
+	}
+
+	data, err := a.secret.Get(a.secretName)
+	if err != nil {
+		a.logger.Info("No saved HUE bridge assignments found, starting from scratch")
+		return
+	}
+
+	err = json.Unmarshal([]byte(data), &a.bridges)
+	if err != nil {
+		a.logger.Error("Saved HUE bridge assignments are corrupted, starting from scratch", err)
+		a.bridges = make(map[string]string)
+	}
+}
+
+// Saves assignments into the secret storage.
+func (a *bridgeAssignments) save() {
+	if nil == a.secret {
+		return
+	}
+
+	data, err := json.Marshal(a.bridges)
+	if err != nil {
+		a.logger.Error("Failed to encode HUE bridge assignments", err)
+		return
+	}
+
+	err = a.secret.Set(a.secretName, string(data))
+	if err != nil {
+		a.logger.Error("Failed to save HUE bridge assignments, devices could be moved after restart", err)
+	}
+}
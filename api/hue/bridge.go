@@ -0,0 +1,362 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes HUE API version reported by emulator.
+	bridgeAPIVersion = "1.16.0"
+	// Describes HUE firmware version reported by emulator.
+	bridgeSWVersion = "1709131301"
+	// Describes HUE bridge model reported by emulator.
+	bridgeModelID = "BSB002"
+	// Describes HUE time format.
+	bridgeTimeFormat = "2006-01-02T15:04:05"
+	// Describes HUE error type for invalid requests.
+	hueErrorInvalidBody = 2
+	// Describes HUE error type for missing parameters.
+	hueErrorMissingParameters = 5
+)
+
+// ErrorResponse describes HUE error.
+type ErrorResponse struct {
+	Error struct {
+		Type        int    `json:"type"`
+		Address     string `json:"address"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// Emulated HUE bridge. Worker runs one bridge per configured
+// advertised address, every bridge exposes its own devices.
+type virtualBridge struct {
+	sync.Mutex
+
+	emulator *HueEmulator
+	settings *BridgeSettings
+	logger   common.ILoggerProvider
+	ids      *idAllocator
+	upnp     *discoverUPNP
+	listener *net.TCPListener
+
+	name         string
+	uuid         string
+	serialNumber string
+	mac          string
+	bridgeID     string
+	ip           string
+	netmask      string
+	users        map[string]*WhitelistEntry
+	numDevices   int
+}
+
+// Constructs a new virtual bridge and prepares its identity
+// based on the settings and advertised address.
+func newVirtualBridge(e *HueEmulator, settings *BridgeSettings, index int,
+	secret common.ISecretProvider) *virtualBridge {
+	ip := strings.Split(settings.AdvAddress, ":")[0]
+	b := &virtualBridge{
+		emulator:     e,
+		settings:     settings,
+		logger:       e.logger,
+		ids:          newIDAllocator(e.logger, secret, settings.IDsSecret, e.Settings.PinnedIDs),
+		name:         settings.FriendlyName,
+		uuid:         settings.UUID,
+		serialNumber: settings.SerialNumber,
+		ip:           ip,
+		netmask:      "255.255.255.0",
+		mac:          "00:00:00:00:00:00",
+		users:        make(map[string]*WhitelistEntry),
+	}
+
+	iface, mask := findInterface(net.ParseIP(ip), settings.Interface)
+	if iface != nil && len(iface.HardwareAddr) > 0 {
+		b.mac = getVirtualMAC(iface.HardwareAddr, index)
+	} else {
+		b.logger.Warn("Failed to find network interface for the advertised address",
+			common.LogDeviceHostToken, ip)
+	}
+
+	if mask != nil {
+		b.netmask = net.IP(mask).String()
+	}
+
+	// Real bridges are using EUI-64 based on the MAC address.
+	mac := strings.ToUpper(strings.Replace(b.mac, ":", "", -1))
+	b.bridgeID = mac[:6] + "FFFE" + mac[6:]
+
+	if "" == b.serialNumber {
+		b.serialNumber = strings.ToLower(mac)
+	}
+
+	// Same UUID prefix is used by real bridges.
+	if "" == b.uuid {
+		b.uuid = "2f402f80-da50-11e1-9b23-" + strings.ToLower(mac)
+	}
+
+	b.upnp = &discoverUPNP{
+		logger:         b.logger,
+		advAddress:     settings.AdvAddress,
+		uuid:           b.uuid,
+		serialNumber:   b.serialNumber,
+		friendlyName:   b.name,
+		bridgeID:       b.bridgeID,
+		iface:          settings.Interface,
+		notifyInterval: time.Duration(e.Settings.NotifyInterval) * time.Second,
+	}
+
+	return b
+}
+
+// Start binds bridge HTTP API and starts UPNP discovery.
+func (b *virtualBridge) Start() error {
+	// It was validated before plugin's load
+	parts := strings.Split(b.settings.AdvAddress, ":")
+	port, _ := strconv.Atoi(parts[1]) // nolint: gosec
+
+	l, err := net.ListenTCP("tcp4", &net.TCPAddr{
+		IP:   net.ParseIP("0.0.0.0"),
+		Port: port,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "tcp bind failed")
+	}
+
+	b.listener = l
+	err = b.upnp.Start()
+	if err != nil {
+		return errors.Wrap(err, "upnp start failed")
+	}
+
+	router := httprouter.New()
+	router.GET("/upnp/setup.xml", b.upnp.Setup)
+	router.POST("/api", b.registerUser)
+	router.GET("/api/:userID", b.getFullState)
+	router.GET("/api/:userID/config", b.getBridgeConfig)
+	router.GET("/api/:userID/lights", b.getDevices)
+	router.PUT("/api/:userID/lights/:lightID/state", b.setDeviceState)
+	router.GET("/api/:userID/lights/:lightID", b.getDeviceInfo)
+	router.GET("/api/:userID/groups", b.getGroups)
+	router.GET("/api/:userID/groups/:groupID", b.getGroupInfo)
+	router.PUT("/api/:userID/groups/:groupID/action", b.setGroupAction)
+
+	go func() {
+		err := http.Serve(b.listener, router)
+		if err != nil {
+			return
+		}
+	}()
+
+	b.logger.Info("Started HUE bridge", common.LogDeviceHostToken, b.settings.AdvAddress)
+	return nil
+}
+
+// Stop stops bridge HTTP API and UPNP discovery.
+//noinspection GoUnhandledErrorResult
+func (b *virtualBridge) Stop() {
+	if b.listener != nil {
+		b.listener.Close() // nolint: gosec, errcheck
+	}
+
+	if b.upnp.connection != nil {
+		b.upnp.Stop()
+	}
+}
+
+// Checks whether bridge has a free slot for a new device.
+// Reserved slots are kept for previously assigned devices.
+// Should be called under emulator lock.
+func (b *virtualBridge) hasCapacity(reserved int) bool {
+	return 0 == b.settings.MaxDevices || b.numDevices+reserved < b.settings.MaxDevices
+}
+
+// Checks whether bridge filter matches the device.
+func (b *virtualBridge) matches(deviceID string) bool {
+	for _, v := range b.settings.devRegexp {
+		if v.Match(deviceID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Responds to CreateUser API. Emulator doesn't require link button to be pressed.
+//noinspection GoUnhandledErrorResult
+func (b *virtualBridge) registerUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	defer r.Body.Close() // nolint: errcheck
+	req := &RegisterCmd{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		b.logger.Error("Failed to decode user registration request", err)
+		b.emulator.sendJSON(w, getErrorResponse(hueErrorInvalidBody, "/", "body contains invalid json"))
+		return
+	}
+
+	if "" == req.DeviceType {
+		b.emulator.sendJSON(w, getErrorResponse(hueErrorMissingParameters, "/", "invalid/missing parameters in body"))
+		return
+	}
+
+	user := randomHex(20)
+	now := time.Now().UTC().Format(bridgeTimeFormat)
+
+	b.Lock()
+	b.users[user] = &WhitelistEntry{
+		Name:        req.DeviceType,
+		CreateDate:  now,
+		LastUseDate: now,
+	}
+	b.Unlock()
+
+	b.logger.Info("Registered a new HUE user", common.LogUserNameToken, req.DeviceType)
+
+	success := map[string]string{"username": user}
+	if req.GenerateClientKey {
+		success["clientkey"] = strings.ToUpper(randomHex(16))
+	}
+
+	var res [1]struct {
+		Success map[string]string `json:"success"`
+	}
+	res[0].Success = success
+
+	b.emulator.sendJSON(w, &res)
+}
+
+// Responds to GetConfig API.
+func (b *virtualBridge) getBridgeConfig(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	b.logger.Debug("Bridge config is requested")
+	b.emulator.sendJSON(w, b.getConfig(params.ByName("userID")))
+}
+
+// Returns bridge config document.
+func (b *virtualBridge) getConfig(userID string) *Config {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	whitelist := make(map[string]*WhitelistEntry)
+	for k, v := range b.users {
+		whitelist[k] = v
+	}
+
+	if u, ok := b.users[userID]; ok {
+		u.LastUseDate = now.UTC().Format(bridgeTimeFormat)
+	}
+
+	return &Config{
+		Name:             b.name,
+		BridgeID:         b.bridgeID,
+		MAC:              b.mac,
+		IPAddress:        b.ip,
+		Netmask:          b.netmask,
+		Gateway:          b.ip,
+		DHCP:             true,
+		APIVersion:       bridgeAPIVersion,
+		SWVersion:        bridgeSWVersion,
+		ModelID:          bridgeModelID,
+		DataStoreVersion: "63",
+		ZigbeeChannel:    15,
+		LinkButton:       true,
+		FactoryNew:       false,
+		StarterKitID:     "",
+		UTC:              now.UTC().Format(bridgeTimeFormat),
+		LocalTime:        now.Format(bridgeTimeFormat),
+		TimeZone:         now.Location().String(),
+		Whitelist:        whitelist,
+	}
+}
+
+// Returns HUE error response.
+func getErrorResponse(errType int, address string, description string) interface{} {
+	return []*ErrorResponse{newErrorResponse(errType, address, description)}
+}
+
+// Constructs a new HUE error entry.
+func newErrorResponse(errType int, address string, description string) *ErrorResponse {
+	res := &ErrorResponse{}
+	res.Error.Type = errType
+	res.Error.Address = address
+	res.Error.Description = description
+
+	return res
+}
+
+// Searches for the network interface which has provided IP.
+// If interface name is provided, only this interface is checked.
+func findInterface(ip net.IP, name string) (*net.Interface, net.IPMask) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, v := range interfaces {
+		v := v
+		if "" != name && name != v.Name {
+			continue
+		}
+
+		addresses, err := v.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, a := range addresses {
+			n, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			if n.IP.Equal(ip) {
+				return &v, n.Mask
+			}
+		}
+
+		if "" != name {
+			return &v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Returns MAC address for the bridge. Every additional bridge on the same
+// interface gets locally administered address, so bridge IDs are unique.
+func getVirtualMAC(hw net.HardwareAddr, index int) string {
+	if 0 == index {
+		return hw.String()
+	}
+
+	mac := make(net.HardwareAddr, len(hw))
+	copy(mac, hw)
+	mac[0] |= 0x02
+	mac[len(mac)-1] += byte(index)
+
+	return mac.String()
+}
+
+// Generates random hex string.
+func randomHex(size int) string {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return strings.Repeat("0", size*2)
+	}
+
+	return hex.EncodeToString(b)
+}
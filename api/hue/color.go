@@ -0,0 +1,212 @@
+package main
+
+import (
+	"math"
+
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes maximum possible HUE value.
+	hueMax = math.MaxUint16
+	// Describes maximum possible saturation value.
+	saturationMax = math.MaxUint8 - 1
+	// Describes the coldest supported color temperature, in mireds.
+	ctMin = 153
+	// Describes the warmest supported color temperature, in mireds.
+	ctMax = 500
+
+	// Describes color mode set by CIE coordinates.
+	colorModeXY = "xy"
+	// Describes color mode set by hue and saturation.
+	colorModeHS = "hs"
+	// Describes color mode set by color temperature.
+	colorModeCT = "ct"
+)
+
+// List of HUE state attributes supported by emulator, in the response order.
+var stateAttributes = []string{"on", "bri", "xy", "hue", "sat", "ct"}
+
+// Converts RGB color into CIE.
+func rgb2cie(color common.Color) [2]float64 {
+	r := rgb2cieMagic(color.R)
+	g := rgb2cieMagic(color.G)
+	b := rgb2cieMagic(color.B)
+
+	x := r*0.649926 + g*0.103455 + b*0.197109
+	y := r*0.234327 + g*0.743075 + b*0.022598
+	z := g*0.053077 + b*1.035763
+
+	if 0 == x+y+z {
+		return [2]float64{0, 0}
+	}
+
+	return [2]float64{roundCIE(x / (x + y + z)), roundCIE(y / (x + y + z))}
+}
+
+// Magic numbers around HUE implementation, while converting RGB into CIE.
+func rgb2cieMagic(c uint8) float64 {
+	correctedValue := float64(c) / math.MaxUint8
+
+	if correctedValue > 0.04045 {
+		return math.Pow((correctedValue+0.055)/(1.0+0.055), 2.4)
+	}
+
+	return correctedValue / 12.92
+}
+
+// Magic numbers around HUE implementation, while converting CIE into RGB.
+func cie2rgbMagic(c float64) float64 {
+	if c <= 0.0031308 {
+		return 12.92 * c
+	}
+
+	return (1.0+0.055)*math.Pow(c, 1.0/2.4) - 0.055
+}
+
+// Converts CIE color into RGB with the full brightness.
+// Brightness is controlled separately by HUE clients.
+func cie2rgb(xy [2]float64) common.Color {
+	x, y := xy[0], xy[1]
+	if 0 == y {
+		return common.Color{R: math.MaxUint8, G: math.MaxUint8, B: math.MaxUint8}
+	}
+
+	z := 1.0 - x - y
+	Y := 1.0
+	X := (Y / y) * x
+	Z := (Y / y) * z
+
+	r := X*1.4628067 - Y*0.1840623 - Z*0.2743606
+	g := -X*0.5217933 + Y*1.4472381 + Z*0.0677227
+	b := X*0.0349342 - Y*0.0968930 + Z*1.2884099
+
+	r = cie2rgbMagic(math.Max(r, 0))
+	g = cie2rgbMagic(math.Max(g, 0))
+	b = cie2rgbMagic(math.Max(b, 0))
+
+	return normalizeRGB(r, g, b)
+}
+
+// Converts HUE hue/saturation into RGB with the full brightness.
+func hs2rgb(hue int, sat int) common.Color {
+	h := float64(clampInt(hue, 0, hueMax)) / hueMax * 360.0
+	s := float64(clampInt(sat, 0, saturationMax)) / saturationMax
+
+	c := s
+	x := c * (1 - math.Abs(math.Mod(h/60.0, 2)-1))
+	m := 1.0 - c
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return normalizeRGB(r+m, g+m, b+m)
+}
+
+// Converts RGB color into HUE hue/saturation.
+func rgb2hs(color common.Color) (int, int) {
+	r := float64(color.R) / math.MaxUint8
+	g := float64(color.G) / math.MaxUint8
+	b := float64(color.B) / math.MaxUint8
+
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	delta := max - min
+
+	if 0 == max || 0 == delta {
+		return 0, 0
+	}
+
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	return int(math.Round(h / 360.0 * hueMax)), int(math.Round(delta / max * saturationMax))
+}
+
+// Converts color temperature in mireds into RGB.
+// Based on Tanner Helland's approximation of the black body radiation.
+func ct2rgb(ct int) common.Color {
+	temp := 1000000.0 / float64(clampInt(ct, ctMin, ctMax)) / 100.0
+
+	var r, g, b float64
+	if temp <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(temp) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(temp-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(temp-60, -0.0755148492)
+	}
+
+	switch {
+	case temp >= 66:
+		b = 255
+	case temp <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(temp-10) - 305.0447927307
+	}
+
+	return common.Color{R: clampColor(r), G: clampColor(g), B: clampColor(b)}
+}
+
+// Scales normalized RGB components so the brightest one is at the maximum.
+func normalizeRGB(r float64, g float64, b float64) common.Color {
+	max := math.Max(r, math.Max(g, b))
+	if max <= 0 {
+		return common.Color{}
+	}
+
+	return common.Color{
+		R: clampColor(r / max * math.MaxUint8),
+		G: clampColor(g / max * math.MaxUint8),
+		B: clampColor(b / max * math.MaxUint8),
+	}
+}
+
+// Returns a valid color component.
+func clampColor(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(math.MaxUint8, v))))
+}
+
+// Returns value within the [min, max] range.
+func clampInt(v int, min int, max int) int {
+	if v < min {
+		return min
+	}
+
+	if v > max {
+		return max
+	}
+
+	return v
+}
+
+// Rounds CIE coordinate the same way real HUE bridge does.
+func roundCIE(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Describes HUE error type for unknown resources.
+	hueErrorResourceNotAvailable = 3
+	// Describes HUE error type for parameters which device doesn't support.
+	hueErrorParameterNotAvailable = 6
+	// Describes HUE error type for internal bridge errors.
+	hueErrorInternal = 901
+)
+
+// Describes failed device command.
+type commandError struct {
+	errType     int
+	description string
+}
+
+// Error returns error description.
+func (e *commandError) Error() string {
+	return e.description
+}
+
+// Constructs a new command error.
+func newCommandError(errType int, format string, args ...interface{}) *commandError {
+	return &commandError{
+		errType:     errType,
+		description: fmt.Sprintf(format, args...),
+	}
+}
+
+// Describes command sent to master which is waiting for acknowledgement.
+type pendingCommand struct {
+	correlationID string
+	attributes    []string
+	result        chan *DeviceUpdateMessage
+}
+
+// Sends command to master and registers it as pending.
+// Attributes are HUE state attributes covered by this command.
+func (e *HueEmulator) sendCommand(cmd *DeviceCommandMessage, attributes ...string) *pendingCommand {
+	p := &pendingCommand{
+		correlationID: randomHex(16),
+		attributes:    attributes,
+		result:        make(chan *DeviceUpdateMessage, 1),
+	}
+
+	e.pendingMutex.Lock()
+	e.pendingCommands[p.correlationID] = p
+	e.pendingMutex.Unlock()
+
+	cmd.CorrelationID = p.correlationID
+	e.communicator.Publish(cmd)
+	return p
+}
+
+// Waits for the command acknowledgement from master.
+func (e *HueEmulator) waitCommand(p *pendingCommand, deadline time.Time) *commandError {
+	defer func() {
+		e.pendingMutex.Lock()
+		delete(e.pendingCommands, p.correlationID)
+		e.pendingMutex.Unlock()
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case ack := <-p.result:
+		if "" == ack.Error {
+			return nil
+		}
+
+		return newCommandError(ack.ErrorType, "%s", ack.Error)
+	case <-timer.C:
+		return newCommandError(hueErrorInternal, "device didn't respond in time")
+	}
+}
+
+// Resolves pending command with the acknowledgement received from master.
+// Returns false if command was sent by another worker.
+func (e *HueEmulator) resolveCommand(ack *DeviceUpdateMessage) bool {
+	e.pendingMutex.Lock()
+	defer e.pendingMutex.Unlock()
+
+	p, ok := e.pendingCommands[ack.CorrelationID]
+	if !ok {
+		return false
+	}
+
+	select {
+	case p.result <- ack:
+	default:
+	}
+
+	return true
+}
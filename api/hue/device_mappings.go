@@ -69,3 +69,78 @@ func setBrightnessDeviceSpecific(internal *DeviceUpdateMessage, percent uint8) *
 		},
 	}
 }
+
+// Transforms internal color state into RGB color.
+func getColorDeviceSpecific(internal *DeviceUpdateMessage) (common.Color, bool) {
+	c, ok := internal.State[enums.PropColor]
+	if !ok {
+		return common.Color{}, false
+	}
+
+	color, err := helpers.UnmarshalProperty(c, enums.PropColor)
+	if err != nil {
+		return common.Color{}, false
+	}
+
+	return color.(common.Color), true
+}
+
+// Transforms received color into device command.
+func setColorDeviceSpecific(internal *DeviceUpdateMessage, color common.Color) *DeviceCommandMessage {
+	if _, ok := internal.State[enums.PropColor]; !ok {
+		return nil
+	}
+
+	return &DeviceCommandMessage{
+		Command:    enums.CmdSetColor,
+		DeviceID:   internal.DeviceID,
+		Attributes: &color,
+	}
+}
+
+// Converts color-related part of the HUE state command into RGB color.
+// Returns color mode which should be reported back to HUE clients.
+func getRequestedColor(internal *DeviceUpdateMessage, req *StateCmd) (common.Color, string, bool) {
+	switch {
+	case req.XY != nil:
+		return cie2rgb(*req.XY), colorModeXY, true
+	case req.Hue != nil || req.Sat != nil:
+		current, _ := getColorDeviceSpecific(internal)
+		hue, sat := rgb2hs(current)
+		if req.Hue != nil {
+			hue = *req.Hue
+		}
+		if req.Sat != nil {
+			sat = *req.Sat
+		}
+
+		return hs2rgb(hue, sat), colorModeHS, true
+	case req.CT != nil:
+		return ct2rgb(*req.CT), colorModeCT, true
+	}
+
+	return common.Color{}, "", false
+}
+
+// Returns HUE state attributes which are covered by the color command.
+// Only the attribute which was used to calculate color is reported.
+func getRequestedColorAttributes(req *StateCmd) []string {
+	switch {
+	case req.XY != nil:
+		return []string{"xy"}
+	case req.Hue != nil || req.Sat != nil:
+		attributes := make([]string, 0)
+		if req.Hue != nil {
+			attributes = append(attributes, "hue")
+		}
+		if req.Sat != nil {
+			attributes = append(attributes, "sat")
+		}
+
+		return attributes
+	case req.CT != nil:
+		return []string{"ct"}
+	}
+
+	return []string{}
+}
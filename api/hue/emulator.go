@@ -1,7 +1,6 @@
 package main
 
 import (
-	"net"
 	"sync"
 
 	"go-home.io/x/server/plugins/api"
@@ -19,13 +18,18 @@ type HueEmulator struct {
 	isMaster     bool
 	communicator api.IExtendedAPICommunicator
 
-	devices            map[string]*DeviceUpdateMessage
-	unsupportedDevices []string
+	devices         map[string]*DeviceUpdateMessage
+	rejectedDevices map[string]*DeviceUpdateMessage
+
+	pendingMutex    sync.Mutex
+	pendingCommands map[string]*pendingCommand
+	updateWaiters   map[string][]chan *DeviceUpdateMessage
 
 	chCommands chan []byte
+	stopChan   chan bool
 
-	upnp     *discoverUPNP
-	listener *net.TCPListener
+	bridges     []*virtualBridge
+	assignments *bridgeAssignments
 }
 
 // Init starts plugin.
@@ -51,9 +55,9 @@ func (e *HueEmulator) Routes() []string {
 //noinspection GoUnhandledErrorResult
 func (e *HueEmulator) Unload() {
 	close(e.chCommands)
+	close(e.stopChan)
 
 	if !e.isMaster {
-		e.listener.Close() // nolint: gosec, errcheck
-		e.upnp.Stop()
+		e.stopBridges()
 	}
 }
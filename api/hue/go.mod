@@ -6,6 +6,7 @@ require (
 	github.com/pkg/errors v0.8.0
 	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
 	golang.org/x/net v0.0.0-20180824045131-faa378e6dbae
+	gopkg.in/yaml.v2 v2.2.1
 )
 
 replace go-home.io/x/server/plugins => ../../../server/plugins
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+const (
+	// Describes HUE special group which contains all lights.
+	allLightsGroupID = "0"
+)
+
+// Responds to GetAllGroups API.
+func (b *virtualBridge) getGroups(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	b.logger.Debug("Groups list is requested")
+	b.emulator.sendJSON(w, b.getGroupsList())
+}
+
+// Responds to GetGroupAttributes API.
+func (b *virtualBridge) getGroupInfo(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+	groupID := params.ByName("groupID")
+	if allLightsGroupID == groupID {
+		b.emulator.sendJSON(w, b.getAllLightsGroup())
+		return
+	}
+
+	v := b.findDevice(groupID, true)
+	if nil == v {
+		b.logger.Warn("Requested unknown group info", common.LogIDToken, groupID)
+		return
+	}
+
+	b.logger.Debug("Requested group info", common.LogIDToken, v.DeviceID)
+	b.emulator.sendJSON(w, getGroup(v))
+}
+
+// Responds to SetGroupState API. Sends command message to master.
+// Special group 0 invokes command on every bridge light.
+//noinspection GoUnhandledErrorResult
+func (b *virtualBridge) setGroupAction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	defer r.Body.Close() // nolint: errcheck
+	req := &StateCmd{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		b.logger.Error("Failed to decode group action request", err)
+		return
+	}
+
+	groupID := params.ByName("groupID")
+	var targets []*DeviceUpdateMessage
+	if allLightsGroupID == groupID {
+		targets = b.getGroupMembers()
+	} else if v := b.findDevice(groupID, true); v != nil {
+		targets = []*DeviceUpdateMessage{v}
+	}
+
+	if 0 == len(targets) {
+		b.logger.Warn("Requested command for unknown group", common.LogIDToken, groupID)
+		b.emulator.sendJSON(w, getErrorResponse(hueErrorResourceNotAvailable, "/groups/"+groupID,
+			"resource, /groups/"+groupID+", not available"))
+		return
+	}
+
+	results := make(map[string]*commandError)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for _, v := range targets {
+		wg.Add(1)
+		go func(v *DeviceUpdateMessage) {
+			defer wg.Done()
+			b.logger.Debug("Requested group command", common.LogIDToken, v.DeviceID)
+			deviceResults := b.emulator.applyState(v, req)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			// Attribute is considered as applied if at least one device accepted it.
+			for k, err := range deviceResults {
+				if prev, ok := results[k]; !ok || prev != nil {
+					results[k] = err
+				}
+			}
+		}(v)
+	}
+
+	wg.Wait()
+	b.emulator.sendJSON(w, getStateResponse("/groups/"+groupID+"/action/", req, results))
+}
+
+// Returns all bridge groups in HUE format.
+func (b *virtualBridge) getGroupsList() map[string]*Group {
+	b.emulator.Lock()
+	defer b.emulator.Unlock()
+
+	response := make(map[string]*Group)
+	for _, v := range b.emulator.devices {
+		if v.bridge != b || v.DeviceType != enums.DevGroup {
+			continue
+		}
+
+		response[v.hueID] = getGroup(v)
+	}
+
+	return response
+}
+
+// Returns all non-group bridge devices.
+func (b *virtualBridge) getGroupMembers() []*DeviceUpdateMessage {
+	b.emulator.Lock()
+	defer b.emulator.Unlock()
+
+	members := make([]*DeviceUpdateMessage, 0)
+	for _, v := range b.emulator.devices {
+		if v.bridge != b || v.DeviceType == enums.DevGroup {
+			continue
+		}
+
+		members = append(members, v)
+	}
+
+	return members
+}
+
+// Returns special group which contains all lights.
+func (b *virtualBridge) getAllLightsGroup() *Group {
+	members := b.getGroupMembers()
+	group := &Group{
+		Name:   "All lights",
+		Type:   "LightGroup",
+		Lights: make([]string, 0),
+		State:  GroupState{AllOn: len(members) > 0},
+		Action: State{
+			Reachable: true,
+			Bri:       brightnessMax,
+		},
+	}
+
+	for _, v := range members {
+		group.Lights = append(group.Lights, v.hueID)
+		isOn := getIsOn(v)
+		group.State.AnyOn = group.State.AnyOn || isOn
+		group.State.AllOn = group.State.AllOn && isOn
+	}
+
+	sort.Strings(group.Lights)
+	group.Action.On = group.State.AnyOn
+	return group
+}
+
+// Wraps internal group state into HUE format.
+// go-home doesn't share group members with extended API,
+// so group is exposed as a single room.
+func getGroup(internal *DeviceUpdateMessage) *Group {
+	light := getDevice(internal)
+	return &Group{
+		Name:   internal.Name,
+		Type:   "Room",
+		Class:  "Other",
+		Lights: make([]string, 0),
+		Action: light.State,
+		State: GroupState{
+			AllOn: light.State.On,
+			AnyOn: light.State.On,
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"go-home.io/x/server/plugins/common"
+)
+
+// Allocates small sequential HUE IDs and keeps them
+// in the secret storage, so consumers see the same lights after restart.
+type idAllocator struct {
+	sync.Mutex
+
+	logger     common.ILoggerProvider
+	secret     common.ISecretProvider
+	secretName string
+
+	ids    map[string]int
+	pinned map[string]int
+}
+
+// Constructs a new IDs allocator and loads previously saved IDs.
+func newIDAllocator(logger common.ILoggerProvider, secret common.ISecretProvider,
+	secretName string, pinned map[string]int) *idAllocator {
+	a := &idAllocator{
+		logger:     logger,
+		secret:     secret,
+		secretName: secretName,
+		ids:        make(map[string]int),
+		pinned:     pinned,
+	}
+
+	a.load()
+	return a
+}
+
+// Get returns HUE ID for the device, allocating a new one if necessary.
+func (a *idAllocator) Get(deviceID string) string {
+	a.Lock()
+	defer a.Unlock()
+
+	if id, ok := a.pinned[deviceID]; ok {
+		return strconv.Itoa(id)
+	}
+
+	if id, ok := a.ids[deviceID]; ok {
+		return strconv.Itoa(id)
+	}
+
+	id := a.nextID()
+	a.ids[deviceID] = id
+	a.save()
+
+	a.logger.Debug("Allocated a new HUE ID", common.LogIDToken, deviceID, "hue_id", strconv.Itoa(id))
+	return strconv.Itoa(id)
+}
+
+// Returns the smallest unused ID. 0 is reserved by HUE for "all lights" group.
+func (a *idAllocator) nextID() int {
+	used := make(map[int]bool)
+	for _, v := range a.pinned {
+		used[v] = true
+	}
+
+	for _, v := range a.ids {
+		used[v] = true
+	}
+
+	id := 1
+	for used[id] {
+		id++
+	}
+
+	return id
+}
+
+// Loads saved IDs from the secret storage.
+// Saved IDs which are conflicting with pinned ones are dropped.
+func (a *idAllocator) load() {
+	if nil == a.secret {
+		return
+	}
+
+	data, err := a.secret.Get(a.secretName)
+	if err != nil {
+		a.logger.Info("No saved HUE IDs found, starting from scratch")
+		return
+	}
+
+	saved := make(map[string]int)
+	err = json.Unmarshal([]byte(data), &saved)
+	if err != nil {
+		a.logger.Error("Saved HUE IDs are corrupted, starting from scratch", err)
+		return
+	}
+
+	pinnedIDs := make(map[int]bool)
+	for _, v := range a.pinned {
+		pinnedIDs[v] = true
+	}
+
+	for k, v := range saved {
+		if _, ok := a.pinned[k]; ok || pinnedIDs[v] || v <= 0 {
+			continue
+		}
+
+		a.ids[k] = v
+	}
+}
+
+// Saves allocated IDs into the secret storage.
+func (a *idAllocator) save() {
+	if nil == a.secret {
+		return
+	}
+
+	data, err := json.Marshal(a.ids)
+	if err != nil {
+		a.logger.Error("Failed to encode HUE IDs", err)
+		return
+	}
+
+	err = a.secret.Set(a.secretName, string(data))
+	if err != nil {
+		a.logger.Error("Failed to save HUE IDs, devices will be re-discovered after restart", err)
+	}
+}
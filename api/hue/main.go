@@ -9,10 +9,13 @@ func Load() (interface{}, interface{}, error) {
 	settings := &Settings{}
 
 	return &HueEmulator{
-			Settings:           settings,
-			devices:            make(map[string]*DeviceUpdateMessage),
-			unsupportedDevices: make([]string, 0),
-			chCommands:         make(chan []byte, 5),
+			Settings:        settings,
+			devices:         make(map[string]*DeviceUpdateMessage),
+			rejectedDevices: make(map[string]*DeviceUpdateMessage),
+			pendingCommands: make(map[string]*pendingCommand),
+			updateWaiters:   make(map[string][]chan *DeviceUpdateMessage),
+			chCommands:      make(chan []byte, 5),
+			stopChan:        make(chan bool),
 		},
 		settings, nil
 }
@@ -2,13 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/gobwas/glob"
 	"github.com/pkg/errors"
 	"go-home.io/x/server/plugins/api"
 	"go-home.io/x/server/plugins/common"
 	"go-home.io/x/server/plugins/device/enums"
-	"go-home.io/x/server/plugins/helpers"
 )
 
 // Init plugin on master node.
@@ -19,6 +19,11 @@ func (e *HueEmulator) initMaster(data *api.InitDataAPI) error {
 		return errors.Wrap(err, "subscription failed")
 	}
 	go e.masterCycle(chUpdate, e.chCommands)
+
+	if "" != e.Settings.SettingsFile {
+		go e.watchSettingsFile()
+	}
+
 	return nil
 }
 
@@ -50,24 +55,67 @@ func (e *HueEmulator) processDeviceCommands(msg []byte) {
 	}
 
 	if cmd.IsDiscovery {
+		e.Lock()
+		devices := make([]*DeviceUpdateMessage, 0, len(e.devices))
 		for _, v := range e.devices {
+			devices = append(devices, v)
+		}
+		e.Unlock()
+
+		for _, v := range devices {
 			e.communicator.Publish(v)
 		}
 
 		return
 	}
 
+	if "" == cmd.CorrelationID {
+		e.invokeCommand(cmd) // nolint: gosec, errcheck
+		return
+	}
+
+	updates := e.waitForUpdate(cmd.DeviceID)
+	if nil == updates {
+		e.acknowledgeCommand(cmd, newCommandError(hueErrorResourceNotAvailable,
+			"resource, %s, not available", cmd.DeviceID))
+		return
+	}
+
+	defer e.stopWaitingForUpdate(cmd.DeviceID, updates)
+
+	cmdErr := e.invokeCommand(cmd)
+	if cmdErr != nil {
+		e.acknowledgeCommand(cmd, cmdErr)
+		return
+	}
+
+	// Extended API doesn't report command results, so master waits for the
+	// device state change. Not every command changes state, e.g. turning on
+	// a light which is already on, so timeout is not an error here.
+	timer := time.NewTimer(time.Duration(e.Settings.CommandTimeout) * time.Second / 2)
+	defer timer.Stop()
+
+	select {
+	case <-updates:
+	case <-timer.C:
+	}
+
+	e.acknowledgeCommand(cmd, nil)
+}
+
+// Invokes device command received from worker.
+func (e *HueEmulator) invokeCommand(cmd *DeviceCommandMessage) *commandError {
 	g, err := glob.Compile(cmd.DeviceID)
 	if err != nil {
 		e.logger.Error("Failed to compile device regexp", err)
-		return
+		return newCommandError(hueErrorInternal, "invalid device ID %s", cmd.DeviceID)
 	}
 
 	a := make(map[string]interface{})
 	data, err := json.Marshal(cmd.Attributes)
 	if err != nil {
 		e.logger.Error("Failed to encode command message", err)
-		return
+		return newCommandError(hueErrorInternal, "invalid command attributes")
 	}
 	err = json.Unmarshal(data, &a)
 	if err != nil {
@@ -75,38 +123,108 @@ func (e *HueEmulator) processDeviceCommands(msg []byte) {
 	}
 
 	e.communicator.InvokeDeviceCommand(g, cmd.Command, a)
+	return nil
 }
 
-// Processes FanOut device updates, converts them to worker msg
-// and send through service bus.
-func (e *HueEmulator) processIncomingDeviceUpdate(msg *common.MsgDeviceUpdate) {
+// Sends command result to workers. Successful result contains the latest known device state.
+func (e *HueEmulator) acknowledgeCommand(cmd *DeviceCommandMessage, cmdErr *commandError) {
 	e.Lock()
 	defer e.Unlock()
 
-	if helpers.SliceContainsString(e.unsupportedDevices, msg.ID) {
-		return
+	ack := &DeviceUpdateMessage{
+		DeviceID:      cmd.DeviceID,
+		CorrelationID: cmd.CorrelationID,
 	}
 
-	var out *DeviceUpdateMessage
+	if cmdErr != nil {
+		ack.Error = cmdErr.description
+		ack.ErrorType = cmdErr.errType
+	} else if known, ok := e.devices[cmd.DeviceID]; ok {
+		ack.Name = known.Name
+		ack.DeviceType = known.DeviceType
+		ack.State = make(map[enums.Property]interface{})
+		for k, v := range known.State {
+			ack.State[k] = v
+		}
+	}
 
-	out, ok := e.devices[msg.ID]
-	if !ok {
-		if !e.isMatch(msg) {
-			e.unsupportedDevices = append(e.unsupportedDevices, msg.ID)
-			return
+	e.communicator.Publish(ack)
+}
+
+// Registers a waiter for the next device state update.
+// Returns nil if device is unknown.
+func (e *HueEmulator) waitForUpdate(deviceID string) chan *DeviceUpdateMessage {
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.devices[deviceID]; !ok {
+		return nil
+	}
+
+	ch := make(chan *DeviceUpdateMessage, 1)
+	e.updateWaiters[deviceID] = append(e.updateWaiters[deviceID], ch)
+	return ch
+}
+
+// Removes device state update waiter.
+func (e *HueEmulator) stopWaitingForUpdate(deviceID string, ch chan *DeviceUpdateMessage) {
+	e.Lock()
+	defer e.Unlock()
+
+	waiters := e.updateWaiters[deviceID]
+	for i, v := range waiters {
+		if v == ch {
+			e.updateWaiters[deviceID] = append(waiters[:i], waiters[i+1:]...)
+			break
 		}
+	}
+
+	if 0 == len(e.updateWaiters[deviceID]) {
+		delete(e.updateWaiters, deviceID)
+	}
+}
 
-		out = &DeviceUpdateMessage{
-			DeviceType: msg.Type,
-			Name:       e.getDeviceName(msg),
-			DeviceID:   msg.ID,
-			State:      make(map[enums.Property]interface{}),
+// Notifies waiters about device state update.
+func (e *HueEmulator) notifyUpdateWaiters(update *DeviceUpdateMessage) {
+	for _, v := range e.updateWaiters[update.DeviceID] {
+		select {
+		case v <- update:
+		default:
 		}
+	}
+}
 
-		e.devices[msg.ID] = out
+// Processes FanOut device updates, converts them to worker msg
+// and send through service bus. Filters are evaluated on every update,
+// so renamed devices and devices with changed type are picked up.
+func (e *HueEmulator) processIncomingDeviceUpdate(msg *common.MsgDeviceUpdate) {
+	e.Lock()
+	defer e.Unlock()
+
+	out, isExposed := e.devices[msg.ID]
+	if !isExposed {
+		var ok bool
+		out, ok = e.rejectedDevices[msg.ID]
+		if !ok {
+			out = &DeviceUpdateMessage{
+				DeviceType: msg.Type,
+				Name:       msg.Name,
+				DeviceID:   msg.ID,
+				State:      make(map[enums.Property]interface{}),
+			}
+		}
 	}
 
 	wasUpdated := false
+	if "" != msg.Name && out.originalName != msg.Name {
+		out.originalName = msg.Name
+		wasUpdated = true
+	}
+
+	if out.DeviceType != msg.Type {
+		out.DeviceType = msg.Type
+		wasUpdated = true
+	}
 
 	for k, v := range msg.State {
 		if !enums.SliceContainsProperty(supportedProperties, k) {
@@ -117,19 +235,64 @@ func (e *HueEmulator) processIncomingDeviceUpdate(msg *common.MsgDeviceUpdate) {
 		wasUpdated = true
 	}
 
-	if wasUpdated {
-		e.communicator.Publish(out)
+	if wasUpdated || !isExposed {
+		e.evaluateDevice(out)
+	}
+}
+
+// Validates whether device should be exposed and notifies workers
+// about the changes. Should be called under emulator lock.
+func (e *HueEmulator) evaluateDevice(out *DeviceUpdateMessage) {
+	_, isExposed := e.devices[out.DeviceID]
+
+	if !e.isMatch(out) {
+		e.rejectedDevices[out.DeviceID] = out
+		if !isExposed {
+			return
+		}
+
+		delete(e.devices, out.DeviceID)
+		e.logger.Info("Device is not exposed anymore", common.LogIDToken, out.DeviceID)
+		e.communicator.Publish(&DeviceUpdateMessage{
+			DeviceID:  out.DeviceID,
+			IsRemoved: true,
+		})
+		return
+	}
+
+	delete(e.rejectedDevices, out.DeviceID)
+	e.devices[out.DeviceID] = out
+	out.Name = e.getDeviceName(out)
+
+	e.communicator.Publish(out)
+	e.notifyUpdateWaiters(out)
+}
+
+// Re-evaluates every known device, e.g. after settings reload.
+// Should be called under emulator lock.
+func (e *HueEmulator) evaluateAllDevices() {
+	all := make([]*DeviceUpdateMessage, 0, len(e.devices)+len(e.rejectedDevices))
+	for _, v := range e.devices {
+		all = append(all, v)
+	}
+
+	for _, v := range e.rejectedDevices {
+		all = append(all, v)
+	}
+
+	for _, v := range all {
+		e.evaluateDevice(v)
 	}
 }
 
 // Validates whether device matches filter regexps and its type is supported.
-func (e *HueEmulator) isMatch(msg *common.MsgDeviceUpdate) bool {
-	if !enums.SliceContainsDeviceType(e.Settings.types, msg.Type) {
+func (e *HueEmulator) isMatch(msg *DeviceUpdateMessage) bool {
+	if !enums.SliceContainsDeviceType(e.Settings.types, msg.DeviceType) {
 		return false
 	}
 
 	for _, v := range e.Settings.devRegexp {
-		if v.Match(msg.ID) {
+		if v.Match(msg.DeviceID) {
 			return true
 		}
 	}
@@ -137,12 +300,12 @@ func (e *HueEmulator) isMatch(msg *common.MsgDeviceUpdate) bool {
 	return false
 }
 
-// Returns either overwritten name or uses deviceID
-func (e *HueEmulator) getDeviceName(msg *common.MsgDeviceUpdate) string {
-	name, ok := e.Settings.NamesOverrides[msg.ID]
+// Returns either overwritten name or uses device name.
+func (e *HueEmulator) getDeviceName(msg *DeviceUpdateMessage) string {
+	name, ok := e.Settings.NamesOverrides[msg.DeviceID]
 	if ok {
 		return name
 	}
 
-	return msg.Name
+	return msg.originalName
 }
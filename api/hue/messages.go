@@ -7,24 +7,35 @@ import (
 
 // DeviceUpdateMessage has data about device update.
 // This message is produced by master.
+// If CorrelationID is set, message acknowledges worker's command.
+// If IsRemoved is set, device shouldn't be exposed anymore.
 type DeviceUpdateMessage struct {
 	api.ExtendedAPIMessage
-	Name       string                         `json:"n"`
-	DeviceType enums.DeviceType               `json:"t"`
-	DeviceID   string                         `json:"i"`
-	State      map[enums.Property]interface{} `json:"s"`
+	Name          string                         `json:"n"`
+	DeviceType    enums.DeviceType               `json:"t"`
+	DeviceID      string                         `json:"i"`
+	State         map[enums.Property]interface{} `json:"s"`
+	CorrelationID string                         `json:"r,omitempty"`
+	Error         string                         `json:"e,omitempty"`
+	ErrorType     int                            `json:"et,omitempty"`
+	IsRemoved     bool                           `json:"x,omitempty"`
 
-	internalHash string
+	bridge       *virtualBridge
+	originalName string
+	hueID        string
+	colorMode    string
+	ct           int
 }
 
 // DeviceCommandMessage has data with new device command.
 // This message is produced by worker.
 type DeviceCommandMessage struct {
 	api.ExtendedAPIMessage
-	IsDiscovery bool          `json:"d"`
-	DeviceID    string        `json:"i"`
-	Command     enums.Command `json:"c"`
-	Attributes  interface{}   `json:"a"`
+	IsDiscovery   bool          `json:"d"`
+	DeviceID      string        `json:"i"`
+	Command       enums.Command `json:"c"`
+	Attributes    interface{}   `json:"a"`
+	CorrelationID string        `json:"r,omitempty"`
 }
 
 // State describes HUE light state.
@@ -62,13 +73,73 @@ type Light struct {
 	} `json:"pointsymbol"`
 }
 
-// LightsList describes list of light known by HUE hub.
-type LightsList struct {
+// FullState describes everything known by HUE hub.
+type FullState struct {
 	Lights map[string]*Light `json:"lights"`
+	Groups map[string]*Group `json:"groups"`
+	Config *Config           `json:"config"`
+}
+
+// GroupState describes aggregated state of HUE group.
+type GroupState struct {
+	AllOn bool `json:"all_on"`
+	AnyOn bool `json:"any_on"`
+}
+
+// Group describes HUE group.
+type Group struct {
+	Name   string     `json:"name"`
+	Lights []string   `json:"lights"`
+	Type   string     `json:"type"`
+	Class  string     `json:"class"`
+	Action State      `json:"action"`
+	State  GroupState `json:"state"`
+}
+
+// WhitelistEntry describes user registered on HUE hub.
+type WhitelistEntry struct {
+	Name        string `json:"name"`
+	LastUseDate string `json:"last use date"`
+	CreateDate  string `json:"create date"`
+}
+
+// Config describes HUE hub configuration.
+type Config struct {
+	Name             string                     `json:"name"`
+	BridgeID         string                     `json:"bridgeid"`
+	MAC              string                     `json:"mac"`
+	IPAddress        string                     `json:"ipaddress"`
+	Netmask          string                     `json:"netmask"`
+	Gateway          string                     `json:"gateway"`
+	DHCP             bool                       `json:"dhcp"`
+	APIVersion       string                     `json:"apiversion"`
+	SWVersion        string                     `json:"swversion"`
+	ModelID          string                     `json:"modelid"`
+	DataStoreVersion string                     `json:"datastoreversion"`
+	ZigbeeChannel    int                        `json:"zigbeechannel"`
+	LinkButton       bool                       `json:"linkbutton"`
+	PortalServices   bool                       `json:"portalservices"`
+	FactoryNew       bool                       `json:"factorynew"`
+	ReplacesBridgeID *string                    `json:"replacesbridgeid"`
+	StarterKitID     string                     `json:"starterkitid"`
+	UTC              string                     `json:"UTC"`
+	LocalTime        string                     `json:"localtime"`
+	TimeZone         string                     `json:"timezone"`
+	Whitelist        map[string]*WhitelistEntry `json:"whitelist"`
+}
+
+// RegisterCmd describes user registration request sent to HUE hub.
+type RegisterCmd struct {
+	DeviceType        string `json:"devicetype"`
+	GenerateClientKey bool   `json:"generateclientkey"`
 }
 
 // StateCmd describes light command sent to HUE hub.
 type StateCmd struct {
-	On  *bool `json:"on"`
-	Bri *int  `json:"bri"`
+	On  *bool       `json:"on"`
+	Bri *int        `json:"bri"`
+	Hue *int        `json:"hue"`
+	Sat *int        `json:"sat"`
+	CT  *int        `json:"ct"`
+	XY  *[2]float64 `json:"xy"`
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/device/enums"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// Describes how often settings file is checked for changes.
+	settingsFilePollInterval = 10 * time.Second
+)
+
+// Part of the settings which could be changed without plugin restart.
+type reloadableSettings struct {
+	DeviceFilter   []string           `yaml:"devices"`
+	DeviceTypes    []enums.DeviceType `yaml:"types"`
+	NamesOverrides map[string]string  `yaml:"nameOverrides"`
+}
+
+// Watches settings file and applies devices filters, types
+// and names overrides once file is changed.
+func (e *HueEmulator) watchSettingsFile() {
+	var lastModified time.Time
+	ticker := time.NewTicker(settingsFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(e.Settings.SettingsFile)
+		if err != nil {
+			e.logger.Warn("Failed to check settings file", "file", e.Settings.SettingsFile,
+				"reason", err.Error())
+		} else if info.ModTime() != lastModified {
+			lastModified = info.ModTime()
+			err = e.reloadSettings()
+			if err != nil {
+				e.logger.Error("Failed to reload settings", err, "file", e.Settings.SettingsFile)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+// Reads settings file and re-evaluates every known device.
+func (e *HueEmulator) reloadSettings() error {
+	data, err := ioutil.ReadFile(e.Settings.SettingsFile)
+	if err != nil {
+		return errors.Wrap(err, "read failed")
+	}
+
+	r := &reloadableSettings{}
+	err = yaml.Unmarshal(data, r)
+	if err != nil {
+		return errors.Wrap(err, "yaml unmarshal failed")
+	}
+
+	s := &Settings{
+		DeviceFilter:   r.DeviceFilter,
+		DeviceTypes:    r.DeviceTypes,
+		NamesOverrides: r.NamesOverrides,
+	}
+
+	err = s.validateFilters()
+	if err != nil {
+		return errors.Wrap(err, "validation failed")
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.Settings.DeviceFilter = s.DeviceFilter
+	e.Settings.DeviceTypes = s.DeviceTypes
+	e.Settings.NamesOverrides = s.NamesOverrides
+	e.Settings.devRegexp = s.devRegexp
+	e.Settings.types = s.types
+
+	e.evaluateAllDevices()
+	e.logger.Info("Reloaded settings", "file", e.Settings.SettingsFile)
+	return nil
+}
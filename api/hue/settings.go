@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"math"
+	"net"
 
 	"github.com/gobwas/glob"
 	"github.com/pkg/errors"
@@ -13,12 +15,37 @@ const (
 	brightnessMax = math.MaxUint8 - 1
 )
 
+// BridgeSettings has data required to start a single virtual HUE bridge.
+type BridgeSettings struct {
+	AdvAddress   string   `yaml:"advAddress" validate:"required,ipv4port"`
+	DeviceFilter []string `yaml:"devices"`
+	MaxDevices   int      `yaml:"maxDevices" validate:"gte=0"`
+	UUID         string   `yaml:"uuid"`
+	SerialNumber string   `yaml:"serial"`
+	FriendlyName string   `yaml:"friendlyName"`
+	Interface    string   `yaml:"interface"`
+	IDsSecret    string   `yaml:"idsSecret"`
+
+	devRegexp []glob.Glob
+}
+
 // Settings has data required to start API.
+// Top-level bridge settings are used if no bridges are defined.
 type Settings struct {
-	AdvAddress     string             `yaml:"advAddress" validate:"required,ipv4port"`
+	AdvAddress     string             `yaml:"advAddress" validate:"omitempty,ipv4port"`
+	Bridges        []*BridgeSettings  `yaml:"bridges" validate:"omitempty,dive"`
 	DeviceFilter   []string           `yaml:"devices"`
 	DeviceTypes    []enums.DeviceType `yaml:"types"`
 	NamesOverrides map[string]string  `yaml:"nameOverrides"`
+	PinnedIDs      map[string]int     `yaml:"ids"`
+	IDsSecret      string             `yaml:"idsSecret" default:"hue-emulator-ids"`
+	CommandTimeout int                `yaml:"commandTimeout" validate:"gt=0" default:"3"`
+	UUID           string             `yaml:"uuid"`
+	SerialNumber   string             `yaml:"serial"`
+	FriendlyName   string             `yaml:"friendlyName" default:"go-home"`
+	Interface      string             `yaml:"interface"`
+	NotifyInterval int                `yaml:"notifyInterval" validate:"gt=0" default:"60"`
+	SettingsFile   string             `yaml:"settingsFile"`
 
 	devRegexp []glob.Glob
 	types     []enums.DeviceType
@@ -26,10 +53,30 @@ type Settings struct {
 
 // Validate performs config validation.
 func (s *Settings) Validate() error {
-	if 0 == len(s.DeviceFilter) {
-		s.DeviceFilter = []string{"**"}
+	err := s.validateFilters()
+	if err != nil {
+		return err
+	}
+
+	usedIDs := make(map[int]string)
+	for k, v := range s.PinnedIDs {
+		if v <= 0 {
+			return errors.Errorf("pinned ID for %s should be positive", k)
+		}
+
+		if d, ok := usedIDs[v]; ok {
+			return errors.Errorf("pinned ID %d is used by both %s and %s", v, d, k)
+		}
+
+		usedIDs[v] = k
 	}
 
+	return s.validateBridges()
+}
+
+// Validates devices filters and types.
+// These settings could be reloaded from the settings file.
+func (s *Settings) validateFilters() error {
 	s.types = make([]enums.DeviceType, 0)
 	for _, v := range s.DeviceTypes {
 		if !enums.SliceContainsDeviceType(supportedTypes, v) {
@@ -43,21 +90,86 @@ func (s *Settings) Validate() error {
 		s.types = supportedTypes
 	}
 
-	s.devRegexp = make([]glob.Glob, 0)
-	for _, v := range s.DeviceFilter {
-		a, err := glob.Compile(v)
+	var err error
+	s.devRegexp, err = compileFilter(s.DeviceFilter)
+	return err
+}
+
+// Validates bridges settings. If no bridges are defined,
+// top-level settings are used for the single bridge.
+func (s *Settings) validateBridges() error {
+	if 0 == len(s.Bridges) {
+		if "" == s.AdvAddress {
+			return errors.New("either advAddress or bridges should be defined")
+		}
+
+		s.Bridges = []*BridgeSettings{{
+			AdvAddress:   s.AdvAddress,
+			UUID:         s.UUID,
+			SerialNumber: s.SerialNumber,
+			FriendlyName: s.FriendlyName,
+			Interface:    s.Interface,
+		}}
+	}
+
+	ports := make(map[string]bool)
+	for i, v := range s.Bridges {
+		_, port, err := net.SplitHostPort(v.AdvAddress)
 		if err != nil {
-			return errors.Wrap(err, "glob compile failed")
+			return errors.Wrap(err, "invalid bridge address")
+		}
+
+		if ports[port] {
+			return errors.Errorf("port %s is used by several bridges", port)
+		}
+
+		ports[port] = true
+
+		if "" == v.FriendlyName {
+			v.FriendlyName = fmt.Sprintf("%s %d", s.FriendlyName, i+1)
+		}
+
+		if "" == v.Interface {
+			v.Interface = s.Interface
+		}
+
+		if "" == v.IDsSecret {
+			v.IDsSecret = s.IDsSecret
+			if i > 0 {
+				v.IDsSecret = fmt.Sprintf("%s-%d", s.IDsSecret, i)
+			}
 		}
 
-		s.devRegexp = append(s.devRegexp, a)
+		v.devRegexp, err = compileFilter(v.DeviceFilter)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Compiles devices filter. Empty filter matches every device.
+func compileFilter(filter []string) ([]glob.Glob, error) {
+	if 0 == len(filter) {
+		filter = []string{"**"}
+	}
+
+	compiled := make([]glob.Glob, 0)
+	for _, v := range filter {
+		a, err := glob.Compile(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "glob compile failed")
+		}
+
+		compiled = append(compiled, a)
+	}
+
+	return compiled, nil
+}
+
 // List of supported device types.
 var supportedTypes = []enums.DeviceType{enums.DevLight, enums.DevSwitch, enums.DevGroup, enums.DevVacuum}
 
 // List of supported device properties.
-var supportedProperties = []enums.Property{enums.PropOn, enums.PropBrightness}
+var supportedProperties = []enums.Property{enums.PropOn, enums.PropBrightness, enums.PropColor}
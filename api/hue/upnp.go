@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -16,12 +17,41 @@ import (
 	"golang.org/x/net/ipv4"
 )
 
+const (
+	// Describes SSDP alive notification.
+	ssdpAlive = "ssdp:alive"
+	// Describes SSDP bye-bye notification.
+	ssdpByeBye = "ssdp:byebye"
+	// Describes SSDP search for all devices.
+	ssdpAll = "ssdp:all"
+	// Describes UPNP root device search target.
+	upnpRootDevice = "upnp:rootdevice"
+	// Describes UPNP basic device search target.
+	upnpBasicDevice = "urn:schemas-upnp-org:device:basic:1"
+	// Describes how long UPNP clients should cache emulator details.
+	upnpMaxAge = 300
+)
+
+// Describes SSDP multicast group.
+var ssdpAddress = &net.UDPAddr{
+	IP:   net.IPv4(239, 255, 255, 250),
+	Port: 1900,
+}
+
 // Discovery provider.
 type discoverUPNP struct {
-	logger     common.ILoggerProvider
-	advAddress string
+	logger         common.ILoggerProvider
+	advAddress     string
+	uuid           string
+	serialNumber   string
+	friendlyName   string
+	bridgeID       string
+	iface          string
+	notifyInterval time.Duration
 
 	connection *net.UDPConn
+	interfaces []net.Interface
+	stopChan   chan bool
 }
 
 // Start starts new UPNP server.
@@ -46,7 +76,7 @@ func (d *discoverUPNP) Start() error {
 	}
 
 	addr := &net.UDPAddr{
-		IP: net.IPv4(239, 255, 255, 250),
+		IP: ssdpAddress.IP,
 	}
 
 	var joined []string
@@ -56,12 +86,17 @@ func (d *discoverUPNP) Start() error {
 			continue
 		}
 
+		if "" != d.iface && v.Name != d.iface {
+			continue
+		}
+
 		err = p.JoinGroup(&v, addr)
 		if err != nil {
 			continue
 		}
 
 		joined = append(joined, v.Name)
+		d.interfaces = append(d.interfaces, v)
 	}
 
 	if len(joined) == 0 {
@@ -72,12 +107,19 @@ func (d *discoverUPNP) Start() error {
 
 	d.logger.Debug("Started UPNP server", "addresses", strings.Join(joined, " "))
 
+	d.stopChan = make(chan bool)
 	go d.listen()
+	go d.notifyCycle()
 	return nil
 }
 
 // Stop stops running UPNP server.
 func (d *discoverUPNP) Stop() {
+	if d.stopChan != nil {
+		close(d.stopChan)
+		d.notify(ssdpByeBye)
+	}
+
 	d.connection.Close() // nolint: gosec, errcheck
 }
 
@@ -98,19 +140,56 @@ func (d *discoverUPNP) listen() {
 		}
 
 		if req.Method != "M-SEARCH" || req.URL.Path != "*" ||
-			req.Header.Get("Man") != `"ssdp:discover"` ||
-			req.Header.Get("St") != `urn:schemas-upnp-org:device:basic:1` {
+			req.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+
+		targets := d.getSearchTargets(req.Header.Get("St"))
+		if 0 == len(targets) {
 			continue
 		}
 
 		d.logger.Info("Received discovery request", "address", add.String())
-		d.discoveryRespond(add)
+		for _, v := range targets {
+			d.discoveryRespond(add, v)
+		}
+	}
+}
+
+// Returns search targets emulator should respond to.
+func (d *discoverUPNP) getSearchTargets(st string) []string {
+	switch st {
+	case ssdpAll:
+		return d.getNotificationTypes()
+	case upnpRootDevice, upnpBasicDevice, "uuid:" + d.uuid:
+		return []string{st}
+	}
+
+	return nil
+}
+
+// Returns all notification types emulator is announcing.
+func (d *discoverUPNP) getNotificationTypes() []string {
+	return []string{upnpRootDevice, "uuid:" + d.uuid, upnpBasicDevice}
+}
+
+// Returns unique service name for the notification type.
+func (d *discoverUPNP) getUSN(nt string) string {
+	if nt == "uuid:"+d.uuid {
+		return nt
 	}
+
+	return fmt.Sprintf("uuid:%s::%s", d.uuid, nt)
+}
+
+// Returns setup XML address.
+func (d *discoverUPNP) getLocation() string {
+	return fmt.Sprintf("http://%s/upnp/setup.xml", d.advAddress)
 }
 
 // Responds to discovery message with advertising address.
 //noinspection GoUnhandledErrorResult
-func (d *discoverUPNP) discoveryRespond(addr *net.UDPAddr) {
+func (d *discoverUPNP) discoveryRespond(addr *net.UDPAddr, st string) {
 	c, err := net.DialUDP("udp", nil, addr)
 	if err != nil {
 		d.logger.Error("Discovery respond error", err)
@@ -118,38 +197,113 @@ func (d *discoverUPNP) discoveryRespond(addr *net.UDPAddr) {
 	}
 	defer c.Close() // nolint: errcheck
 
-	url := fmt.Sprintf("http://%s/upnp/setup.xml", d.advAddress)
-
-	var buf bytes.Buffer
-	_, err = buf.WriteString("HTTP/1.1 200 OK\r\n")
+	data, err := d.buildMessage("HTTP/1.1 200 OK", http.Header{
+		"Cache-Control": {fmt.Sprintf("max-age=%d", upnpMaxAge)},
+		"Ext":           {``},
+		"Location":      {d.getLocation()},
+		"Opt":           {`"http://schemas.upnp.org/upnp/1/0/"; ns=01`},
+		"Server":        {"Linux/3.14.0 UPnP/1.0 IpBridge/" + bridgeAPIVersion},
+		"Hue-Bridgeid":  {d.bridgeID},
+		"St":            {st},
+		"Usn":           {d.getUSN(st)},
+	})
 	if err != nil {
-		d.logger.Error("Error writing UPnP http", err)
+		d.logger.Error("Error writing UPnP response", err)
 		return
 	}
 
-	err = http.Header{
-		"Cache-Control": {`max-age=300`},
-		"Ext":           {``},
-		"Location":      {url},
-		"Opt":           {`"http://schemas.upnp.org/upnp/1/0/"; ns=01`},
-		"St":            {`urn:schemas-upnp-org:device:basic:1`},
-		"Usn":           {`uuid:f6543a06-800d-48ba-8d8f-bc2949eddc33`},
-	}.Write(&buf)
+	_, err = c.Write(data)
 	if err != nil {
-		d.logger.Error("Error writing UPnP headers", err)
-		return
+		d.logger.Error("Error writing UPnP response", err)
 	}
+}
 
-	_, err = buf.WriteString("\r\n")
+// Periodically announces emulator to the network.
+func (d *discoverUPNP) notifyCycle() {
+	d.notify(ssdpAlive)
+
+	ticker := time.NewTicker(d.notifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.notify(ssdpAlive)
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+// Sends NOTIFY message to every joined interface.
+//noinspection GoUnhandledErrorResult
+func (d *discoverUPNP) notify(nts string) {
+	c, err := net.ListenUDP("udp4", nil)
 	if err != nil {
-		d.logger.Error("Error writing UPnP finish", err)
+		d.logger.Error("Failed to open UPnP notify connection", err)
 		return
 	}
+	defer c.Close() // nolint: errcheck
+
+	p := ipv4.NewPacketConn(c)
+	for _, nt := range d.getNotificationTypes() {
+		header := http.Header{
+			"Host":         {ssdpAddress.String()},
+			"Nt":           {nt},
+			"Nts":          {nts},
+			"Usn":          {d.getUSN(nt)},
+			"Hue-Bridgeid": {d.bridgeID},
+		}
 
-	_, err = c.Write(buf.Bytes())
+		if nts == ssdpAlive {
+			header["Cache-Control"] = []string{fmt.Sprintf("max-age=%d", upnpMaxAge)}
+			header["Location"] = []string{d.getLocation()}
+			header["Server"] = []string{"Linux/3.14.0 UPnP/1.0 IpBridge/" + bridgeAPIVersion}
+		}
+
+		data, err := d.buildMessage("NOTIFY * HTTP/1.1", header)
+		if err != nil {
+			d.logger.Error("Error writing UPnP notification", err)
+			return
+		}
+
+		for _, v := range d.interfaces {
+			v := v
+			err = p.SetMulticastInterface(&v)
+			if err != nil {
+				d.logger.Error("Failed to set multicast interface", err, "interface", v.Name)
+				continue
+			}
+
+			_, err = p.WriteTo(data, nil, ssdpAddress)
+			if err != nil {
+				d.logger.Error("Error sending UPnP notification", err, "interface", v.Name)
+			}
+		}
+	}
+
+	d.logger.Debug("Sent UPnP notification", "type", nts)
+}
+
+// Builds SSDP message.
+func (d *discoverUPNP) buildMessage(startLine string, header http.Header) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buf.WriteString(startLine + "\r\n")
 	if err != nil {
-		d.logger.Error("Error writing UPnP response", err)
+		return nil, errors.Wrap(err, "start line write failed")
 	}
+
+	err = header.Write(&buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "headers write failed")
+	}
+
+	_, err = buf.WriteString("\r\n")
+	if err != nil {
+		return nil, errors.Wrap(err, "finish write failed")
+	}
+
+	return buf.Bytes(), nil
 }
 
 // Setup replies on initial HTTP discovery request
@@ -168,6 +322,7 @@ func (d *discoverUPNP) Setup(w http.ResponseWriter, _ *http.Request, _ httproute
 		Manufacturer string `xml:"device>manufacturer"`
 		ModelName    string `xml:"device>modelName"`
 		ModelNumber  string `xml:"device>modelNumber"`
+		SerialNumber string `xml:"device>serialNumber"`
 		UDN          string `xml:"device>UDN"`
 	}
 
@@ -178,11 +333,12 @@ func (d *discoverUPNP) Setup(w http.ResponseWriter, _ *http.Request, _ httproute
 		URLBase: "http://" + d.advAddress + "/",
 
 		DeviceType:   "urn:schemas-upnp-org:device:Basic:1",
-		FriendlyName: "go-home",
+		FriendlyName: d.friendlyName,
 		Manufacturer: "Royal Philips Electronics",
 		ModelName:    "Philips hue bridge 2012",
 		ModelNumber:  "929000226503",
-		UDN:          "uuid:f6543a06-800d-48ba-8d8f-bc2949eddc33",
+		SerialNumber: d.serialNumber,
+		UDN:          "uuid:" + d.uuid,
 	}
 
 	w.Header().Set("Content-Type", "application/xml")
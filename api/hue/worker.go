@@ -3,12 +3,9 @@ package main
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"hash/fnv"
-	"net"
 	"net/http"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -19,52 +16,63 @@ import (
 )
 
 // Init plugin on worker node.
-func (e *HueEmulator) initWorker(*api.InitDataAPI) error {
-	e.upnp = &discoverUPNP{
-		logger:     e.logger,
-		advAddress: e.Settings.AdvAddress,
+func (e *HueEmulator) initWorker(data *api.InitDataAPI) error {
+	e.assignments = newBridgeAssignments(e.logger, data.Secret, e.Settings.IDsSecret+"-bridges")
+	e.bridges = make([]*virtualBridge, 0)
+	for i, v := range e.Settings.Bridges {
+		b := newVirtualBridge(e, v, i, data.Secret)
+		err := b.Start()
+		if err != nil {
+			b.Stop()
+			e.stopBridges()
+			return errors.Wrap(err, "bridge start failed")
+		}
+
+		e.bridges = append(e.bridges, b)
 	}
 
-	// It was validated before plugin's load
-	parts := strings.Split(e.Settings.AdvAddress, ":")
-	port, _ := strconv.Atoi(parts[1]) // nolint: gosec
+	err := e.communicator.Subscribe(e.chCommands)
+	if err != nil {
+		e.stopBridges()
+		return errors.Wrap(err, "bus subscription failed")
+	}
 
-	l, err := net.ListenTCP("tcp4", &net.TCPAddr{
-		IP:   net.ParseIP("0.0.0.0"),
-		Port: port,
+	go e.workerCycle(e.chCommands)
+	e.communicator.Publish(&DeviceCommandMessage{
+		IsDiscovery: true,
 	})
+	return nil
+}
 
-	if err != nil {
-		return errors.Wrap(err, "tcp bind failed")
+// Stops all started bridges.
+func (e *HueEmulator) stopBridges() {
+	for _, v := range e.bridges {
+		v.Stop()
 	}
+}
 
-	e.listener = l
-	err = e.upnp.Start()
-	if err != nil {
-		return errors.Wrap(err, "upnp start failed")
+// Picks the bridge which exposed the device before, so it keeps HUE ID.
+// New devices are placed on the first matching bridge with a free slot.
+// Should be called under emulator lock.
+func (e *HueEmulator) assignBridge(deviceID string) *virtualBridge {
+	if name, ok := e.assignments.Get(deviceID); ok {
+		for _, v := range e.bridges {
+			if name == v.settings.IDsSecret && v.matches(deviceID) {
+				v.numDevices++
+				return v
+			}
+		}
 	}
 
-	router := httprouter.New()
-	router.GET("/upnp/setup.xml", e.upnp.Setup)
-	router.GET("/api/:userID", e.getDevices)
-	router.PUT("/api/:userID/lights/:lightID/state", e.setDeviceState)
-	router.GET("/api/:userID/lights/:lightID", e.getDeviceInfo)
-
-	go func() {
-		err := http.Serve(e.listener, router)
-		if err != nil {
-			return
+	for _, v := range e.bridges {
+		if v.matches(deviceID) && v.hasCapacity(e.assignments.Reserved(v.settings.IDsSecret, e.devices)) {
+			v.numDevices++
+			e.assignments.Set(deviceID, v.settings.IDsSecret)
+			return v
 		}
-	}()
-	err = e.communicator.Subscribe(e.chCommands)
-	if err != nil {
-		return errors.Wrap(err, "bus subscription failed")
 	}
 
-	go e.workerCycle(e.chCommands)
-	e.communicator.Publish(&DeviceCommandMessage{
-		IsDiscovery: true,
-	})
+	e.logger.Warn("None of HUE bridges can expose the device", common.LogIDToken, deviceID)
 	return nil
 }
 
@@ -88,99 +96,249 @@ func (e *HueEmulator) processDeviceUpdate(msg []byte) {
 		return
 	}
 
-	var dHash string
+	if "" != update.CorrelationID && e.resolveCommand(update) {
+		e.logger.Debug("Received command acknowledgement", common.LogIDToken, update.DeviceID)
+	}
+
 	old, ok := e.devices[update.DeviceID]
+	if update.IsRemoved {
+		if ok {
+			e.logger.Debug("Device is no longer exposed", common.LogIDToken, update.DeviceID)
+			if old.bridge != nil {
+				old.bridge.numDevices--
+			}
+
+			delete(e.devices, update.DeviceID)
+		}
+
+		return
+	}
+
+	if nil == update.State {
+		return
+	}
+
 	if ok {
-		dHash = old.internalHash
-	} else {
-		dHash = hash(update.DeviceID)
+		update.bridge = old.bridge
+		update.hueID = old.hueID
+		update.colorMode = old.colorMode
+		update.ct = old.ct
+	} else if update.bridge = e.assignBridge(update.DeviceID); update.bridge != nil {
+		update.hueID = update.bridge.ids.Get(update.DeviceID)
 	}
 
 	e.devices[update.DeviceID] = update
-	e.devices[update.DeviceID].internalHash = dHash
 }
 
-// Responds to GetAllLights API.
-func (e *HueEmulator) getDevices(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	e.logger.Debug("Devices list is requested")
-	response := make(map[string]*Light)
-
-	for _, v := range e.devices {
-		response[v.internalHash] = getDevice(v)
-	}
-
-	e.sendJSON(w, &LightsList{
-		Lights: response,
+// Responds to GetFullState API.
+func (b *virtualBridge) getFullState(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	b.logger.Debug("Full state is requested")
+	b.emulator.sendJSON(w, &FullState{
+		Lights: b.getLightsList(),
+		Groups: b.getGroupsList(),
+		Config: b.getConfig(params.ByName("userID")),
 	})
 }
 
+// Responds to GetAllLights API.
+func (b *virtualBridge) getDevices(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	b.logger.Debug("Devices list is requested")
+	b.emulator.sendJSON(w, b.getLightsList())
+}
+
 // Responds to GetLightInfo API.
-func (e *HueEmulator) getDeviceInfo(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
+func (b *virtualBridge) getDeviceInfo(w http.ResponseWriter, _ *http.Request, params httprouter.Params) {
 	lightID := params.ByName("lightID")
-	for _, v := range e.devices {
-		if lightID == v.internalHash {
-			e.logger.Debug("Requested device state info", common.LogIDToken, v.DeviceID)
-			e.sendJSON(w, getDevice(v))
-			return
-		}
+	v := b.findDevice(lightID, false)
+	if nil == v {
+		b.logger.Warn("Requested unknown device state info", common.LogIDToken, lightID)
+		return
 	}
 
-	e.logger.Warn("Requested unknown device state info", common.LogIDToken, lightID)
+	b.logger.Debug("Requested device state info", common.LogIDToken, v.DeviceID)
+	b.emulator.sendJSON(w, getDevice(v))
 }
 
 // Updates device state API. Sends command message to master.
 //noinspection GoUnhandledErrorResult
-func (e *HueEmulator) setDeviceState(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+func (b *virtualBridge) setDeviceState(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	defer r.Body.Close() // nolint: errcheck
 	req := &StateCmd{}
 	err := json.NewDecoder(r.Body).Decode(req)
 	if err != nil {
-		e.logger.Error("Failed to decode state change request", err)
+		b.logger.Error("Failed to decode state change request", err)
 		return
 	}
 
 	lightID := params.ByName("lightID")
-	for _, v := range e.devices {
-		if lightID == v.internalHash {
-			e.logger.Debug("Requested device command", common.LogIDToken, v.DeviceID)
-			if req.On != nil {
-				cmd := enums.CmdOn
-				if !*req.On {
-					cmd = enums.CmdOff
-				}
-
-				e.communicator.Publish(&DeviceCommandMessage{
-					Command:    cmd,
-					DeviceID:   v.DeviceID,
-					Attributes: nil,
-				})
-			}
+	v := b.findDevice(lightID, false)
+	if nil == v {
+		b.logger.Warn("Requested command for unknown device", common.LogIDToken, lightID)
+		b.emulator.sendJSON(w, getErrorResponse(hueErrorResourceNotAvailable, "/lights/"+lightID,
+			"resource, /lights/"+lightID+", not available"))
+		return
+	}
 
-			if req.Bri != nil {
-				cmd := setBrightnessDeviceSpecific(v,
-					uint8((float32(*req.Bri)*100.0)/float32(brightnessMax)))
-				if cmd != nil {
-					e.communicator.Publish(cmd)
-				}
-			}
+	b.logger.Debug("Requested device command", common.LogIDToken, v.DeviceID)
+	results := b.emulator.applyState(v, req)
+	b.emulator.sendJSON(w, getStateResponse("/lights/"+lightID+"/state/", req, results))
+}
 
-			m := make(map[string]interface{})
-			if req.On != nil {
-				m["/lights/"+lightID+"/state/on"] = *req.On
-			}
-			if req.Bri != nil {
-				m["/lights/"+lightID+"/state/bri"] = *req.Bri
+// Returns all non-group bridge devices in HUE format.
+func (b *virtualBridge) getLightsList() map[string]*Light {
+	b.emulator.Lock()
+	defer b.emulator.Unlock()
+
+	response := make(map[string]*Light)
+	for _, v := range b.emulator.devices {
+		if v.bridge != b || v.DeviceType == enums.DevGroup {
+			continue
+		}
+
+		response[v.hueID] = getDevice(v)
+	}
+
+	return response
+}
+
+// Searches for a bridge device or a group by its HUE ID.
+func (b *virtualBridge) findDevice(hueID string, isGroup bool) *DeviceUpdateMessage {
+	b.emulator.Lock()
+	defer b.emulator.Unlock()
+
+	for _, v := range b.emulator.devices {
+		if v.bridge == b && hueID == v.hueID && isGroup == (v.DeviceType == enums.DevGroup) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// Converts HUE state command into go-home commands, sends them to master
+// and waits for acknowledgements. Returns results per HUE state attribute.
+func (e *HueEmulator) applyState(v *DeviceUpdateMessage, req *StateCmd) map[string]*commandError {
+	results := make(map[string]*commandError)
+	pending := make([]*pendingCommand, 0)
+
+	if req.On != nil {
+		cmd := enums.CmdOn
+		if !*req.On {
+			cmd = enums.CmdOff
+		}
+
+		results["on"] = nil
+		pending = append(pending, e.sendCommand(&DeviceCommandMessage{
+			Command:    cmd,
+			DeviceID:   v.DeviceID,
+			Attributes: nil,
+		}, "on"))
+	}
+
+	if req.Bri != nil {
+		cmd := setBrightnessDeviceSpecific(v,
+			uint8((float32(*req.Bri)*100.0)/float32(brightnessMax)))
+		if cmd != nil {
+			results["bri"] = nil
+			pending = append(pending, e.sendCommand(cmd, "bri"))
+		} else {
+			results["bri"] = newCommandError(hueErrorParameterNotAvailable,
+				"parameter, bri, is not available")
+		}
+	}
+
+	var colorCmd *pendingCommand
+	color, mode, ok := getRequestedColor(v, req)
+	if ok {
+		attributes := getRequestedColorAttributes(req)
+		cmd := setColorDeviceSpecific(v, color)
+		for _, a := range attributes {
+			if cmd != nil {
+				results[a] = nil
+			} else {
+				results[a] = newCommandError(hueErrorParameterNotAvailable,
+					"parameter, %s, is not available", a)
 			}
+		}
+
+		if cmd != nil {
+			colorCmd = e.sendCommand(cmd, attributes...)
+			pending = append(pending, colorCmd)
+		}
+	}
+
+	deadline := time.Now().Add(time.Duration(e.Settings.CommandTimeout) * time.Second)
+	for _, p := range pending {
+		err := e.waitCommand(p, deadline)
+		for _, a := range p.attributes {
+			results[a] = err
+		}
+
+		if err != nil {
+			e.logger.Warn("Device command failed", common.LogIDToken, v.DeviceID,
+				"attributes", strings.Join(p.attributes, ","), "reason", err.Error())
+			continue
+		}
+
+		if p != colorCmd {
+			continue
+		}
 
-			var res [1]struct {
-				Success map[string]interface{} `json:"success"`
+		// Device update might have replaced the snapshot while command was running.
+		e.Lock()
+		if current, ok := e.devices[v.DeviceID]; ok {
+			current.colorMode = mode
+			if req.CT != nil {
+				current.ct = clampInt(*req.CT, ctMin, ctMax)
 			}
-			res[0].Success = m
+		}
+		e.Unlock()
+	}
+
+	return results
+}
+
+// Returns HUE response for the state command.
+// Every requested attribute produces either success or error entry.
+func getStateResponse(prefix string, req *StateCmd, results map[string]*commandError) interface{} {
+	values := make(map[string]interface{})
+	if req.On != nil {
+		values["on"] = *req.On
+	}
+	if req.Bri != nil {
+		values["bri"] = *req.Bri
+	}
+	if req.XY != nil {
+		values["xy"] = *req.XY
+	}
+	if req.Hue != nil {
+		values["hue"] = *req.Hue
+	}
+	if req.Sat != nil {
+		values["sat"] = *req.Sat
+	}
+	if req.CT != nil {
+		values["ct"] = *req.CT
+	}
 
-			e.sendJSON(w, &res)
-			return
+	res := make([]interface{}, 0)
+	for _, a := range stateAttributes {
+		val, ok := values[a]
+		if !ok {
+			continue
 		}
+
+		if err := results[a]; err != nil {
+			res = append(res, newErrorResponse(err.errType, prefix+a, err.description))
+			continue
+		}
+
+		res = append(res, map[string]interface{}{
+			"success": map[string]interface{}{prefix + a: val},
+		})
 	}
+
+	return res
 }
 
 // Wraps object to JSON.
@@ -197,9 +355,9 @@ func (e *HueEmulator) sendJSON(w http.ResponseWriter, val interface{}) {
 
 // Wraps internal device state into HUE format.
 func getDevice(internal *DeviceUpdateMessage) *Light {
-	return &Light{
-		Type:             "Extended color light",
-		ModelID:          "LCT001",
+	light := &Light{
+		Type:             "Dimmable light",
+		ModelID:          "LWB004",
 		SWVersion:        "65003148",
 		ManufacturerName: "Philips",
 		Name:             internal.Name,
@@ -210,6 +368,28 @@ func getDevice(internal *DeviceUpdateMessage) *Light {
 			On:        getIsOn(internal),
 		},
 	}
+
+	color, ok := getColorDeviceSpecific(internal)
+	if !ok {
+		return light
+	}
+
+	light.Type = "Extended color light"
+	light.ModelID = "LCT001"
+	light.State.XY = rgb2cie(color)
+	light.State.Hue, light.State.Sat = rgb2hs(color)
+	light.State.ColorMode = internal.colorMode
+	light.State.CT = internal.ct
+
+	if "" == light.State.ColorMode {
+		light.State.ColorMode = colorModeXY
+	}
+
+	if 0 == light.State.CT {
+		light.State.CT = ctMin
+	}
+
+	return light
 }
 
 // Transforms internal brightness to HUE format.
@@ -236,14 +416,3 @@ func getIsOn(internal *DeviceUpdateMessage) bool {
 
 	return on.(bool)
 }
-
-// Returns simple int hash for the deviceID. This is required since
-// emulator-consumers are using same int ID for calling get/set state API.
-func hash(s string) string {
-	h := fnv.New32a()
-	_, err := h.Write([]byte(s))
-	if err != nil {
-		return ""
-	}
-	return fmt.Sprint(h.Sum32())
-}
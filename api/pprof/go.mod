@@ -1,8 +1,10 @@
 module go-home.io/x/providers/api/pprof
 
-// go: no requirements found in Gopkg.lock
-
-require go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+require (
+	github.com/gorilla/mux v1.6.2
+	github.com/pkg/errors v0.8.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
 
 replace go-home.io/x/server/plugins => ../../../server/plugins
 
@@ -4,5 +4,10 @@ package main
 // Load is the main plugin entry point.
 // nolint: deadcode
 func Load() (interface{}, interface{}, error) {
-	return &PprofAPI{}, nil, nil
+	settings := &Settings{}
+
+	return &PprofAPI{
+			Settings: settings,
+		},
+		settings, nil
 }
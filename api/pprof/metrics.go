@@ -0,0 +1,133 @@
+package main
+
+import (
+	"expvar"
+	"io/ioutil"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// Describes expvar route. Handler is registered by expvar package.
+	varsRoute = "/debug/vars"
+	// Describes expvar map which is shared with other providers.
+	// Provider can register a counter using
+	// expvar.Get("plugins").(*expvar.Map).Add("my-counter", 1).
+	pluginsVar = "plugins"
+	// Describes number of recent GC pauses reported.
+	recentPauses = 10
+)
+
+// Publishes runtime metrics. Expvar variables are global,
+// so it's safe to call this function after plugin reload.
+func publishRuntimeMetrics() {
+	publishFunc("goroutines", func() interface{} {
+		return runtime.NumGoroutine()
+	})
+	publishFunc("gc", getGCStats)
+	publishFunc("memory", getMemoryStats)
+	publishFunc("fds", getOpenFDs)
+	publishFunc("build", getBuildInfo)
+
+	if nil == expvar.Get(pluginsVar) {
+		expvar.NewMap(pluginsVar)
+	}
+}
+
+// Publishes expvar function, if it wasn't published before.
+func publishFunc(name string, f func() interface{}) {
+	if nil != expvar.Get(name) {
+		return
+	}
+
+	expvar.Publish(name, expvar.Func(f))
+}
+
+// Returns garbage collector stats.
+func getGCStats() interface{} {
+	stats := &debug.GCStats{
+		PauseQuantiles: make([]time.Duration, 5),
+	}
+	debug.ReadGCStats(stats)
+
+	pauses := stats.Pause
+	if len(pauses) > recentPauses {
+		pauses = pauses[:recentPauses]
+	}
+
+	return map[string]interface{}{
+		"num_gc":          stats.NumGC,
+		"last_gc":         stats.LastGC,
+		"pause_total_ns":  stats.PauseTotal.Nanoseconds(),
+		"recent_pauses":   durationsToNs(pauses),
+		"pause_quantiles": durationsToNs(stats.PauseQuantiles),
+	}
+}
+
+// Returns memory stats.
+func getMemoryStats() interface{} {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return map[string]interface{}{
+		"alloc":         m.Alloc,
+		"total_alloc":   m.TotalAlloc,
+		"sys":           m.Sys,
+		"heap_alloc":    m.HeapAlloc,
+		"heap_sys":      m.HeapSys,
+		"heap_idle":     m.HeapIdle,
+		"heap_inuse":    m.HeapInuse,
+		"heap_released": m.HeapReleased,
+		"heap_objects":  m.HeapObjects,
+		"stack_inuse":   m.StackInuse,
+		"mallocs":       m.Mallocs,
+		"frees":         m.Frees,
+	}
+}
+
+// Returns number of open file descriptors.
+// Returns -1 if OS doesn't provide procfs.
+func getOpenFDs() interface{} {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	return len(fds)
+}
+
+// Returns binary build info.
+func getBuildInfo() interface{} {
+	res := map[string]interface{}{
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return res
+	}
+
+	res["path"] = info.Path
+	res["version"] = info.Main.Version
+
+	deps := make(map[string]string)
+	for _, v := range info.Deps {
+		deps[v.Path] = v.Version
+	}
+
+	res["deps"] = deps
+	return res
+}
+
+// Converts durations into nanoseconds.
+func durationsToNs(durations []time.Duration) []int64 {
+	res := make([]int64, 0, len(durations))
+	for _, v := range durations {
+		res = append(res, v.Nanoseconds())
+	}
+
+	return res
+}
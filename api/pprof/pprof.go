@@ -3,27 +3,63 @@ package main
 import (
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 
+	"github.com/pkg/errors"
 	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
 )
 
 // PprofAPI is a pprof wrapper.
 type PprofAPI struct {
+	Settings *Settings
+
+	logger    common.ILoggerProvider
+	snapshots *snapshotsStorage
+	stopChan  chan bool
 }
 
 // Init makes an attempt to setup a new pprof API extension.
-func (*PprofAPI) Init(data *api.InitDataAPI) error {
+func (p *PprofAPI) Init(data *api.InitDataAPI) error {
+	p.logger = data.Logger
+	p.stopChan = make(chan bool)
+
+	err := os.MkdirAll(p.Settings.SnapshotsPath, 0700)
+	if err != nil {
+		return errors.Wrap(err, "snapshots folder create failed")
+	}
+
+	p.snapshots = &snapshotsStorage{
+		logger:       p.logger,
+		path:         p.Settings.SnapshotsPath,
+		maxSnapshots: p.Settings.MaxSnapshots,
+	}
+
+	// Snapshot routes should be registered before the catch-all prefix.
+	data.InternalRootRouter.HandleFunc(snapshotsRoute, p.listSnapshots).Methods(http.MethodGet)
+	data.InternalRootRouter.HandleFunc(snapshotsRoute+"/{type}", p.captureSnapshot).Methods(http.MethodPost)
+	data.InternalRootRouter.HandleFunc(snapshotsRoute+"/{name}", p.getSnapshot).Methods(http.MethodGet)
 	data.InternalRootRouter.PathPrefix("/debug/").Handler(http.DefaultServeMux)
+
+	publishRuntimeMetrics()
+
+	if p.Settings.GoroutinesThreshold > 0 || p.Settings.HeapThreshold > 0 {
+		go p.watchdog()
+	}
+
 	return nil
 }
 
 // Routes returns registered routes.
 func (*PprofAPI) Routes() []string {
-	return []string{"/debug/pprof"}
+	return []string{"/debug/pprof", varsRoute, snapshotsRoute}
 }
 
 // Unload is responsible for plugin unload.
-// This plugin runs on server side only
-// and we don't have anything to stop.
-func (*PprofAPI) Unload() {
+// This plugin runs on server side only,
+// so we only need to stop the watchdog.
+func (p *PprofAPI) Unload() {
+	if p.stopChan != nil {
+		close(p.stopChan)
+	}
 }
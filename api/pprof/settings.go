@@ -0,0 +1,17 @@
+package main
+
+// Settings has data required to start API.
+type Settings struct {
+	SnapshotsPath       string `yaml:"snapshotsPath" default:"/tmp/go-home-pprof"`
+	MaxSnapshots        int    `yaml:"maxSnapshots" validate:"gt=0" default:"10"`
+	MaxDuration         int    `yaml:"maxDuration" validate:"gt=0" default:"60"`
+	GoroutinesThreshold int    `yaml:"goroutinesThreshold" validate:"gte=0"`
+	HeapThreshold       int    `yaml:"heapThreshold" validate:"gte=0"`
+	CheckInterval       int    `yaml:"checkInterval" validate:"gt=0" default:"30"`
+	Cooldown            int    `yaml:"cooldown" validate:"gt=0" default:"300"`
+}
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes snapshots route.
+	snapshotsRoute = "/debug/snapshots"
+	// Describes time format used in snapshot names.
+	snapshotTimeFormat = "20060102-150405.000"
+	// Describes default duration of CPU profile and trace, in seconds.
+	defaultSnapshotDuration = 30
+
+	// Describes CPU profile snapshot.
+	snapshotCPU = "cpu"
+	// Describes heap profile snapshot.
+	snapshotHeap = "heap"
+	// Describes goroutines dump snapshot.
+	snapshotGoroutine = "goroutine"
+	// Describes execution trace snapshot.
+	snapshotTrace = "trace"
+)
+
+// Returned if another snapshot is being captured.
+var errSnapshotInProgress = errors.New("another snapshot is in progress")
+
+// Snapshot describes saved profile.
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+}
+
+// Keeps the last N snapshots on disk.
+type snapshotsStorage struct {
+	sync.Mutex
+
+	logger       common.ILoggerProvider
+	path         string
+	maxSnapshots int
+	isBusy       bool
+}
+
+// Capture takes a new snapshot and removes the oldest ones.
+// Only one snapshot could be captured at a time.
+//noinspection GoUnhandledErrorResult
+func (s *snapshotsStorage) Capture(snapshotType string, duration time.Duration, reason string) (*Snapshot, error) {
+	ext := "pprof"
+	switch snapshotType {
+	case snapshotCPU, snapshotHeap, snapshotGoroutine:
+	case snapshotTrace:
+		ext = "trace"
+	default:
+		return nil, errors.Errorf("unknown snapshot type %s", snapshotType)
+	}
+
+	s.Lock()
+	if s.isBusy {
+		s.Unlock()
+		return nil, errSnapshotInProgress
+	}
+
+	s.isBusy = true
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		s.isBusy = false
+		s.Unlock()
+	}()
+
+	created := time.Now()
+	name := fmt.Sprintf("%s-%s.%s", snapshotType, created.Format(snapshotTimeFormat), ext)
+	f, err := os.Create(filepath.Join(s.path, name))
+	if err != nil {
+		return nil, errors.Wrap(err, "file create failed")
+	}
+
+	err = writeSnapshot(f, snapshotType, duration)
+	f.Close() // nolint: gosec, errcheck
+	if err != nil {
+		os.Remove(f.Name()) // nolint: gosec, errcheck
+		return nil, errors.Wrap(err, "profile write failed")
+	}
+
+	s.rotate()
+
+	info, err := os.Stat(f.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "file stat failed")
+	}
+
+	s.logger.Info("Captured profile snapshot", "snapshot", name, "reason", reason)
+	return &Snapshot{
+		Name:    name,
+		Type:    snapshotType,
+		Reason:  reason,
+		Created: created,
+		Size:    info.Size(),
+	}, nil
+}
+
+// List returns saved snapshots, newest first.
+func (s *snapshotsStorage) List() []*Snapshot {
+	files, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		s.logger.Error("Failed to read snapshots folder", err)
+		return []*Snapshot{}
+	}
+
+	snapshots := make([]*Snapshot, 0, len(files))
+	for _, v := range files {
+		if v.IsDir() {
+			continue
+		}
+
+		snapshots = append(snapshots, &Snapshot{
+			Name:    v.Name(),
+			Type:    strings.SplitN(v.Name(), "-", 2)[0],
+			Created: v.ModTime(),
+			Size:    v.Size(),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+
+	return snapshots
+}
+
+// Removes snapshots above the limit.
+func (s *snapshotsStorage) rotate() {
+	snapshots := s.List()
+	if len(snapshots) <= s.maxSnapshots {
+		return
+	}
+
+	for _, v := range snapshots[s.maxSnapshots:] {
+		err := os.Remove(filepath.Join(s.path, v.Name))
+		if err != nil {
+			s.logger.Error("Failed to remove old snapshot", err, "snapshot", v.Name)
+		}
+	}
+}
+
+// Writes requested profile into the file.
+func writeSnapshot(f *os.File, snapshotType string, duration time.Duration) error {
+	switch snapshotType {
+	case snapshotCPU:
+		err := pprof.StartCPUProfile(f)
+		if err != nil {
+			return err
+		}
+
+		time.Sleep(duration)
+		pprof.StopCPUProfile()
+	case snapshotTrace:
+		err := trace.Start(f)
+		if err != nil {
+			return err
+		}
+
+		time.Sleep(duration)
+		trace.Stop()
+	case snapshotHeap:
+		runtime.GC()
+		return pprof.Lookup("heap").WriteTo(f, 0)
+	case snapshotGoroutine:
+		return pprof.Lookup("goroutine").WriteTo(f, 0)
+	}
+
+	return nil
+}
+
+// Responds with the list of saved snapshots.
+func (p *PprofAPI) listSnapshots(w http.ResponseWriter, _ *http.Request) {
+	p.sendJSON(w, p.snapshots.List())
+}
+
+// Captures a new snapshot. Duration of CPU profile and trace
+// is set by seconds query parameter.
+func (p *PprofAPI) captureSnapshot(w http.ResponseWriter, r *http.Request) {
+	duration := defaultSnapshotDuration
+	if v := r.URL.Query().Get("seconds"); "" != v {
+		d, err := strconv.Atoi(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "seconds should be a positive number", http.StatusBadRequest)
+			return
+		}
+
+		duration = d
+	}
+
+	if duration > p.Settings.MaxDuration {
+		duration = p.Settings.MaxDuration
+	}
+
+	snapshot, err := p.snapshots.Capture(mux.Vars(r)["type"], time.Duration(duration)*time.Second, "requested")
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == errSnapshotInProgress {
+			status = http.StatusConflict
+		}
+
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	p.sendJSON(w, snapshot)
+}
+
+// Responds with the snapshot file.
+func (p *PprofAPI) getSnapshot(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+name)
+	http.ServeFile(w, r, filepath.Join(p.Settings.SnapshotsPath, name))
+}
+
+// Sends JSON response.
+func (p *PprofAPI) sendJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		p.logger.Error("Failed to send response", err)
+	}
+}
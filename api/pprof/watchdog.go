@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Describes bytes in megabyte.
+const megabyte = 1024 * 1024
+
+// Periodically checks goroutines count and heap size and
+// captures snapshots once thresholds are exceeded.
+func (p *PprofAPI) watchdog() {
+	ticker := time.NewTicker(time.Duration(p.Settings.CheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	cooldown := time.Duration(p.Settings.Cooldown) * time.Second
+	lastSnapshots := make(map[string]time.Time)
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		if p.Settings.GoroutinesThreshold > 0 {
+			num := runtime.NumGoroutine()
+			if num > p.Settings.GoroutinesThreshold && time.Since(lastSnapshots[snapshotGoroutine]) > cooldown {
+				lastSnapshots[snapshotGoroutine] = time.Now()
+				p.autoSnapshot(snapshotGoroutine, fmt.Sprintf("%d goroutines", num))
+			}
+		}
+
+		if p.Settings.HeapThreshold > 0 {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			heap := int(m.HeapAlloc / megabyte)
+			if heap > p.Settings.HeapThreshold && time.Since(lastSnapshots[snapshotHeap]) > cooldown {
+				lastSnapshots[snapshotHeap] = time.Now()
+				p.autoSnapshot(snapshotHeap, fmt.Sprintf("%d MB heap", heap))
+			}
+		}
+	}
+}
+
+// Captures snapshot triggered by the watchdog.
+func (p *PprofAPI) autoSnapshot(snapshotType string, reason string) {
+	p.logger.Warn("Runtime threshold exceeded, capturing snapshot", "snapshot", snapshotType, "reason", reason)
+	_, err := p.snapshots.Capture(snapshotType, 0, reason)
+	if err != nil {
+		p.logger.Error("Failed to capture snapshot", err, "snapshot", snapshotType)
+	}
+}
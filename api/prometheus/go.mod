@@ -0,0 +1,18 @@
+module go-home.io/x/providers/api/prometheus
+
+require (
+	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
+	github.com/gobwas/glob v0.2.3
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/pkg/errors v0.8.0
+	github.com/prometheus/client_golang v0.9.0
+	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910 // indirect
+	github.com/prometheus/common v0.0.0-20181020173914-7e9e6cabbd39 // indirect
+	github.com/prometheus/procfs v0.0.0-20181005140218-185b4288413d // indirect
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+go 1.13
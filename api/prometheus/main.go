@@ -0,0 +1,13 @@
+// Package main contains Prometheus metrics exporter API extension.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &PrometheusAPI{
+			Settings: settings,
+		},
+		settings, nil
+}
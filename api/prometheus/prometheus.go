@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+	"go-home.io/x/server/plugins/helpers"
+)
+
+const (
+	// Describes metrics route.
+	metricsRoute = "/metrics"
+	// Describes device ID label.
+	labelDeviceID = "device_id"
+	// Describes device type label.
+	labelDeviceType = "device_type"
+	// Describes worker ID label.
+	labelWorker = "worker"
+)
+
+// Describes exported properties and corresponding gauge names.
+var exportedProperties = map[enums.Property]string{
+	enums.PropTemperature:  "temperature",
+	enums.PropHumidity:     "humidity",
+	enums.PropBatteryLevel: "battery_level",
+	enums.PropOn:           "on",
+	enums.PropBrightness:   "brightness",
+	enums.PropArea:         "area",
+}
+
+// PrometheusAPI exports devices states as Prometheus gauges.
+type PrometheusAPI struct {
+	sync.Mutex
+	Settings *Settings
+
+	logger         common.ILoggerProvider
+	fanOut         common.IFanOut
+	subscriptionID int64
+	registry       *prometheus.Registry
+	gauges         map[enums.Property]*prometheus.GaugeVec
+	stopChan       chan bool
+}
+
+// Init subscribes to devices updates and registers metrics route.
+// This plugin runs on server side only.
+func (p *PrometheusAPI) Init(data *api.InitDataAPI) error {
+	if !data.IsMaster {
+		return nil
+	}
+
+	p.logger = data.Logger
+	p.fanOut = data.FanOut
+	p.registry = prometheus.NewRegistry()
+	p.gauges = make(map[enums.Property]*prometheus.GaugeVec)
+	p.stopChan = make(chan bool)
+
+	for k, v := range exportedProperties {
+		g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: p.Settings.Namespace,
+			Subsystem: "device",
+			Name:      v,
+			Help:      "Device " + v + " reported by go-home.",
+		}, []string{labelDeviceID, labelDeviceType, labelWorker})
+
+		err := p.registry.Register(g)
+		if err != nil {
+			return errors.Wrap(err, "gauge register failed")
+		}
+
+		p.gauges[k] = g
+	}
+
+	data.InternalRootRouter.Handle(metricsRoute, promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+
+	var chUpdates chan *common.MsgDeviceUpdate
+	p.subscriptionID, chUpdates = p.fanOut.SubscribeDeviceUpdates()
+	go p.updatesCycle(chUpdates)
+	return nil
+}
+
+// Routes returns registered routes.
+func (p *PrometheusAPI) Routes() []string {
+	return []string{metricsRoute}
+}
+
+// Unload stops devices updates processing.
+func (p *PrometheusAPI) Unload() {
+	if nil == p.stopChan {
+		return
+	}
+
+	p.fanOut.UnSubscribeDeviceUpdates(p.subscriptionID)
+	close(p.stopChan)
+}
+
+// Waits for incoming devices updates.
+func (p *PrometheusAPI) updatesCycle(updates chan *common.MsgDeviceUpdate) {
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case msg, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			p.processDeviceUpdate(msg)
+		}
+	}
+}
+
+// Converts device properties into gauges values.
+func (p *PrometheusAPI) processDeviceUpdate(msg *common.MsgDeviceUpdate) {
+	if !p.isMatch(msg.ID) {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	for k, v := range msg.State {
+		g, ok := p.gauges[k]
+		if !ok {
+			continue
+		}
+
+		value, ok := getNumericValue(v, k)
+		if !ok {
+			p.logger.Debug("Skipping non-numeric property", common.LogIDToken, msg.ID,
+				common.LogDevicePropertyToken, k.String())
+			continue
+		}
+
+		g.WithLabelValues(msg.ID, msg.Type.String(), msg.WorkerID).Set(value)
+	}
+}
+
+// Validates whether device matches filter regexps.
+func (p *PrometheusAPI) isMatch(deviceID string) bool {
+	for _, v := range p.Settings.devRegexp {
+		if v.Match(deviceID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Converts property value into float.
+// Complex properties are converted into plain values first.
+func getNumericValue(value interface{}, prop enums.Property) (float64, bool) {
+	if v, ok := toFloat(value); ok {
+		return v, true
+	}
+
+	return toFloat(helpers.PlainProperty(value, prop))
+}
+
+// Converts plain numeric or bool value into float.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+
+		return 0, true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case common.Percent:
+		return float64(v.Value), true
+	case *common.Percent:
+		return float64(v.Value), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+)
+
+// Settings has data required to start API.
+type Settings struct {
+	Namespace    string   `yaml:"namespace" default:"gohome"`
+	DeviceFilter []string `yaml:"devices"`
+
+	devRegexp []glob.Glob
+}
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	if 0 == len(s.DeviceFilter) {
+		s.DeviceFilter = []string{"**"}
+	}
+
+	s.devRegexp = make([]glob.Glob, 0)
+	for _, v := range s.DeviceFilter {
+		a, err := glob.Compile(v)
+		if err != nil {
+			return errors.Wrap(err, "glob compile failed")
+		}
+
+		s.devRegexp = append(s.devRegexp, a)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-home.io/x/server/plugins/common"
+)
+
+// Caches API tokens loaded from the secret storage.
+// Secret contains comma-separated list of tokens.
+type tokensCache struct {
+	sync.Mutex
+
+	logger     common.ILoggerProvider
+	secret     common.ISecretProvider
+	secretName string
+	cacheTime  time.Duration
+
+	tokens   []string
+	loadedAt time.Time
+}
+
+// Constructs a new tokens cache.
+func newTokensCache(logger common.ILoggerProvider, secret common.ISecretProvider,
+	secretName string, cacheTime int) *tokensCache {
+	return &tokensCache{
+		logger:     logger,
+		secret:     secret,
+		secretName: secretName,
+		cacheTime:  time.Duration(cacheTime) * time.Second,
+	}
+}
+
+// IsValid checks whether token is known.
+func (t *tokensCache) IsValid(token string) bool {
+	if "" == token {
+		return false
+	}
+
+	valid := false
+	for _, v := range t.getTokens() {
+		if 1 == subtle.ConstantTimeCompare([]byte(v), []byte(token)) {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// Returns tokens, reloading them if cache is expired.
+func (t *tokensCache) getTokens() []string {
+	t.Lock()
+	defer t.Unlock()
+
+	if time.Since(t.loadedAt) < t.cacheTime {
+		return t.tokens
+	}
+
+	t.loadedAt = time.Now()
+	data, err := t.secret.Get(t.secretName)
+	if err != nil {
+		t.logger.Error("Failed to load REST API tokens", err, "secret", t.secretName)
+		t.tokens = []string{}
+		return t.tokens
+	}
+
+	t.tokens = make([]string, 0)
+	for _, v := range strings.Split(data, ",") {
+		v = strings.TrimSpace(v)
+		if "" != v {
+			t.tokens = append(t.tokens, v)
+		}
+	}
+
+	return t.tokens
+}
+
+// Validates request token. Token is expected either in Authorization header
+// or in token query parameter, since browsers can't set headers for WebSocket
+// and EventSource connections.
+func (r *RestAPI) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if "" == token {
+			token = req.URL.Query().Get("token")
+		}
+
+		if !r.tokens.IsValid(token) {
+			r.logger.Warn("Unauthorized REST API request", common.LogURLToken, req.URL.Path)
+			r.sendError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,12 @@
+module go-home.io/x/providers/api/rest
+
+require (
+	github.com/gobwas/glob v0.2.3
+	github.com/gorilla/mux v1.6.2
+	github.com/gorilla/websocket v1.2.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+go 1.13
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gobwas/glob"
+	"github.com/gorilla/mux"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// Responds with all known devices.
+func (r *RestAPI) getDevices(w http.ResponseWriter, _ *http.Request) {
+	r.sendJSON(w, http.StatusOK, r.getDevicesList())
+}
+
+// Responds with a single device.
+func (r *RestAPI) getDevice(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+
+	r.Lock()
+	d, ok := r.devices[id]
+	if ok {
+		d = copyDevice(d)
+	}
+	r.Unlock()
+
+	if !ok {
+		r.sendError(w, http.StatusNotFound, "device not found")
+		return
+	}
+
+	r.sendJSON(w, http.StatusOK, d)
+}
+
+// Invokes command on every device matching the glob selector.
+//noinspection GoUnhandledErrorResult
+func (r *RestAPI) invokeCommand(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close() // nolint: errcheck
+	cmd := &CommandRequest{}
+	err := json.NewDecoder(req.Body).Decode(cmd)
+	if err != nil {
+		r.sendError(w, http.StatusBadRequest, "body contains invalid json")
+		return
+	}
+
+	if "" == cmd.Devices {
+		r.sendError(w, http.StatusBadRequest, "devices selector is required")
+		return
+	}
+
+	command, err := enums.CommandString(cmd.Command)
+	if err != nil {
+		r.sendError(w, http.StatusBadRequest, "unknown command")
+		return
+	}
+
+	g, err := glob.Compile(cmd.Devices)
+	if err != nil {
+		r.sendError(w, http.StatusBadRequest, "invalid devices selector")
+		return
+	}
+
+	if nil == cmd.Attributes {
+		cmd.Attributes = make(map[string]interface{})
+	}
+
+	matched := r.getMatchedDevices(g)
+	if 0 == len(matched) {
+		r.sendError(w, http.StatusNotFound, "no devices match the selector")
+		return
+	}
+
+	r.logger.Debug("Invoking REST API command", common.LogIDToken, cmd.Devices,
+		common.LogDeviceCommandToken, command.String())
+	r.communicator.InvokeDeviceCommand(g, command, cmd.Attributes)
+
+	r.sendJSON(w, http.StatusAccepted, &CommandResponse{
+		Command: command.String(),
+		Devices: matched,
+	})
+}
+
+// Returns copy of all known devices, sorted by ID.
+func (r *RestAPI) getDevicesList() []*DeviceState {
+	r.Lock()
+	defer r.Unlock()
+
+	res := make([]*DeviceState, 0, len(r.devices))
+	for _, v := range r.devices {
+		res = append(res, copyDevice(v))
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// Returns IDs of known devices matching the selector.
+func (r *RestAPI) getMatchedDevices(g glob.Glob) []string {
+	r.Lock()
+	defer r.Unlock()
+
+	res := make([]string, 0)
+	for k := range r.devices {
+		if g.Match(k) {
+			res = append(res, k)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Sends JSON response.
+func (r *RestAPI) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		r.logger.Error("Failed to send response", err)
+	}
+}
+
+// Sends JSON error.
+func (r *RestAPI) sendError(w http.ResponseWriter, status int, message string) {
+	r.sendJSON(w, status, &ErrorResponse{Error: message})
+}
@@ -0,0 +1,15 @@
+// Package main contains REST and WebSocket devices API extension.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &RestAPI{
+			Settings:    settings,
+			devices:     make(map[string]*DeviceState),
+			subscribers: make(map[int64]chan *DeviceState),
+		},
+		settings, nil
+}
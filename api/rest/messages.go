@@ -0,0 +1,34 @@
+package main
+
+import (
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// DeviceState describes device known by API.
+type DeviceState struct {
+	ID    string                 `json:"id"`
+	Name  string                 `json:"name"`
+	Type  string                 `json:"type"`
+	State map[string]interface{} `json:"state"`
+
+	deviceType enums.DeviceType
+}
+
+// CommandRequest describes command sent to the devices.
+// Devices is a glob selector, e.g. "hub.*.light".
+type CommandRequest struct {
+	Devices    string                 `json:"devices"`
+	Command    string                 `json:"command"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// CommandResponse describes accepted command.
+type CommandResponse struct {
+	Command string   `json:"command"`
+	Devices []string `json:"devices"`
+}
+
+// ErrorResponse describes API error.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"sync"
+
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes API routes prefix.
+	routePrefix = "/rest"
+)
+
+// RestAPI provides generic devices API.
+type RestAPI struct {
+	sync.Mutex
+	Settings *Settings
+
+	logger         common.ILoggerProvider
+	secret         common.ISecretProvider
+	fanOut         common.IFanOut
+	communicator   api.IExtendedAPICommunicator
+	subscriptionID int64
+
+	devices       map[string]*DeviceState
+	subscribers   map[int64]chan *DeviceState
+	lastSubscribe int64
+	tokens        *tokensCache
+	stopChan      chan bool
+}
+
+// Init subscribes to devices updates and registers API routes.
+// This plugin runs on server side only.
+func (r *RestAPI) Init(data *api.InitDataAPI) error {
+	if !data.IsMaster {
+		return nil
+	}
+
+	r.logger = data.Logger
+	r.secret = data.Secret
+	r.fanOut = data.FanOut
+	r.communicator = data.Communicator
+	r.stopChan = make(chan bool)
+	r.tokens = newTokensCache(r.logger, r.secret, r.Settings.TokensSecret, r.Settings.TokensCacheTime)
+
+	router := data.ExternalAPIRouter.PathPrefix(routePrefix).Subrouter()
+	router.Use(r.authMiddleware)
+	router.HandleFunc("/devices", r.getDevices).Methods("GET")
+	router.HandleFunc("/devices/{id}", r.getDevice).Methods("GET")
+	router.HandleFunc("/commands", r.invokeCommand).Methods("POST")
+	router.HandleFunc("/ws", r.streamWebSocket).Methods("GET")
+	router.HandleFunc("/events", r.streamEvents).Methods("GET")
+
+	var chUpdates chan *common.MsgDeviceUpdate
+	r.subscriptionID, chUpdates = r.fanOut.SubscribeDeviceUpdates()
+	go r.updatesCycle(chUpdates)
+	return nil
+}
+
+// Routes returns registered routes.
+func (r *RestAPI) Routes() []string {
+	return []string{
+		routePrefix + "/devices",
+		routePrefix + "/commands",
+		routePrefix + "/ws",
+		routePrefix + "/events",
+	}
+}
+
+// Unload stops devices updates processing and closes streams.
+func (r *RestAPI) Unload() {
+	if nil == r.stopChan {
+		return
+	}
+
+	r.fanOut.UnSubscribeDeviceUpdates(r.subscriptionID)
+	close(r.stopChan)
+
+	r.Lock()
+	defer r.Unlock()
+
+	for k, v := range r.subscribers {
+		close(v)
+		delete(r.subscribers, k)
+	}
+}
+
+// Waits for incoming devices updates.
+func (r *RestAPI) updatesCycle(updates chan *common.MsgDeviceUpdate) {
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case msg, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			r.processDeviceUpdate(msg)
+		}
+	}
+}
+
+// Updates known device state and notifies stream subscribers.
+func (r *RestAPI) processDeviceUpdate(msg *common.MsgDeviceUpdate) {
+	r.Lock()
+	defer r.Unlock()
+
+	d, ok := r.devices[msg.ID]
+	if !ok {
+		d = &DeviceState{
+			ID:         msg.ID,
+			State:      make(map[string]interface{}),
+			deviceType: msg.Type,
+		}
+
+		r.devices[msg.ID] = d
+	}
+
+	if "" != msg.Name {
+		d.Name = msg.Name
+	}
+
+	d.deviceType = msg.Type
+	d.Type = msg.Type.String()
+
+	changes := &DeviceState{
+		ID:    d.ID,
+		Name:  d.Name,
+		Type:  d.Type,
+		State: make(map[string]interface{}),
+	}
+
+	for k, v := range msg.State {
+		d.State[k.String()] = v
+		changes.State[k.String()] = v
+	}
+
+	for _, v := range r.subscribers {
+		select {
+		case v <- changes:
+		default:
+			r.logger.Debug("Stream subscriber is too slow, dropping update", common.LogIDToken, msg.ID)
+		}
+	}
+}
+
+// Subscribes to devices changes.
+func (r *RestAPI) subscribe() (int64, chan *DeviceState) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastSubscribe++
+	ch := make(chan *DeviceState, r.Settings.StreamBuffer)
+	r.subscribers[r.lastSubscribe] = ch
+	return r.lastSubscribe, ch
+}
+
+// Removes devices changes subscription.
+func (r *RestAPI) unsubscribe(id int64) {
+	r.Lock()
+	defer r.Unlock()
+
+	ch, ok := r.subscribers[id]
+	if !ok {
+		return
+	}
+
+	close(ch)
+	delete(r.subscribers, id)
+}
+
+// Returns copy of the device state. Should be called under API lock.
+func copyDevice(d *DeviceState) *DeviceState {
+	c := &DeviceState{
+		ID:    d.ID,
+		Name:  d.Name,
+		Type:  d.Type,
+		State: make(map[string]interface{}),
+	}
+
+	for k, v := range d.State {
+		c.State[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,13 @@
+package main
+
+// Settings has data required to start API.
+type Settings struct {
+	TokensSecret    string `yaml:"tokensSecret" default:"rest-api-tokens"`
+	TokensCacheTime int    `yaml:"tokensCacheTime" validate:"gte=0" default:"60"`
+	StreamBuffer    int    `yaml:"streamBuffer" validate:"gt=0" default:"50"`
+}
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Describes how often keep-alive messages are sent to stream clients.
+	streamPingInterval = 30 * time.Second
+	// Describes WebSocket write timeout.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// WebSocket upgrader. Requests are authorized by token,
+// so cross-origin connections are allowed.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Streams devices changes over WebSocket.
+// All known devices are sent first.
+//noinspection GoUnhandledErrorResult
+func (r *RestAPI) streamWebSocket(w http.ResponseWriter, req *http.Request) {
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		r.logger.Error("Failed to upgrade WebSocket connection", err)
+		return
+	}
+
+	defer conn.Close() // nolint: errcheck
+
+	id, ch := r.subscribe()
+	defer r.unsubscribe(id)
+
+	closed := make(chan bool)
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, v := range r.getDevicesList() {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)) // nolint: gosec, errcheck
+		if conn.WriteJSON(v) != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+		case v, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)) // nolint: gosec, errcheck
+			err = conn.WriteJSON(v)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Streams devices changes as server-sent events.
+// All known devices are sent first.
+func (r *RestAPI) streamEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		r.sendError(w, http.StatusNotImplemented, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	id, ch := r.subscribe()
+	defer r.unsubscribe(id)
+
+	for _, v := range r.getDevicesList() {
+		if r.writeEvent(w, v) != nil {
+			return
+		}
+	}
+
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case v, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			err = r.writeEvent(w, v)
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Writes a single server-sent event.
+func (r *RestAPI) writeEvent(w http.ResponseWriter, d *DeviceState) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		r.logger.Error("Failed to encode device state", err)
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "event: device\ndata: %s\n\n", data)
+	return err
+}
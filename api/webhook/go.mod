@@ -0,0 +1,11 @@
+module go-home.io/x/providers/api/webhook
+
+require (
+	github.com/gobwas/glob v0.2.3
+	github.com/pkg/errors v0.8.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+go 1.13
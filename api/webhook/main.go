@@ -0,0 +1,15 @@
+// Package main contains inbound webhooks implementation for the go-home extended API.
+// Worker accepts HTTP requests and renders commands, master invokes them.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &WebhookAPI{
+			Settings:   settings,
+			chCommands: make(chan []byte, 5),
+		},
+		settings, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Init plugin on master node.
+func (w *WebhookAPI) initMaster() error {
+	err := w.communicator.Subscribe(w.chCommands)
+	if err != nil {
+		return errors.Wrap(err, "subscription failed")
+	}
+
+	go w.masterCycle(w.chCommands)
+	return nil
+}
+
+// Master internal cycle. Waits for incoming commands from worker.
+func (w *WebhookAPI) masterCycle(devCommands chan []byte) {
+	for cmd := range devCommands {
+		go w.processDeviceCommands(cmd)
+	}
+}
+
+// Processes command received from worker.
+func (w *WebhookAPI) processDeviceCommands(msg []byte) {
+	cmd := &DeviceCommandMessage{}
+	err := json.Unmarshal(msg, cmd)
+	if err != nil {
+		w.logger.Error("Received corrupted message", err)
+		return
+	}
+
+	g, err := glob.Compile(cmd.DeviceID)
+	if err != nil {
+		w.logger.Error("Failed to compile device regexp", err)
+		return
+	}
+
+	if nil == cmd.Attributes {
+		cmd.Attributes = make(map[string]interface{})
+	}
+
+	w.logger.Debug("Invoking webhook command", common.LogIDToken, cmd.DeviceID,
+		common.LogDeviceCommandToken, cmd.Command.String())
+	w.communicator.InvokeDeviceCommand(g, cmd.Command, cmd.Attributes)
+}
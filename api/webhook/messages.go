@@ -0,0 +1,26 @@
+package main
+
+import (
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// DeviceCommandMessage has data with new device command.
+// This message is produced by worker. DeviceID is a glob selector.
+type DeviceCommandMessage struct {
+	api.ExtendedAPIMessage
+	DeviceID   string                 `json:"i"`
+	Command    enums.Command          `json:"c"`
+	Attributes map[string]interface{} `json:"a"`
+}
+
+// WebhookResponse describes accepted webhook.
+type WebhookResponse struct {
+	Devices string `json:"devices"`
+	Command string `json:"command"`
+}
+
+// ErrorResponse describes webhook error.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/device/enums"
+	"go-home.io/x/server/plugins/helpers"
+)
+
+// RouteSettings describes a single webhook.
+// Attributes template is rendered with request body and query.
+type RouteSettings struct {
+	Path       string        `yaml:"path" validate:"required"`
+	Secret     string        `yaml:"secret" validate:"required"`
+	Devices    string        `yaml:"devices" validate:"required"`
+	Command    enums.Command `yaml:"command" validate:"required"`
+	Attributes string        `yaml:"attributes"`
+
+	expression helpers.ITemplateExpression
+}
+
+// Settings has data required to start API.
+type Settings struct {
+	Address     string           `yaml:"address" validate:"required,ipv4port"`
+	MaxBodySize int64            `yaml:"maxBodySize" validate:"gt=0" default:"65536"`
+	Routes      []*RouteSettings `yaml:"routes" validate:"required,dive"`
+
+	routes map[string]*RouteSettings
+}
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	s.routes = make(map[string]*RouteSettings)
+	for _, v := range s.Routes {
+		if !strings.HasPrefix(v.Path, "/") {
+			v.Path = "/" + v.Path
+		}
+
+		if _, ok := s.routes[v.Path]; ok {
+			return errors.Errorf("path %s is used by several routes", v.Path)
+		}
+
+		if !v.Command.IsACommand() {
+			return errors.Errorf("route %s has unknown command", v.Path)
+		}
+
+		_, err := glob.Compile(v.Devices)
+		if err != nil {
+			return errors.Wrap(err, "glob compile failed")
+		}
+
+		s.routes[v.Path] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+)
+
+// WebhookAPI implements extended API plugin and
+// provides inbound webhooks.
+type WebhookAPI struct {
+	Settings *Settings
+
+	logger       common.ILoggerProvider
+	isMaster     bool
+	communicator api.IExtendedAPICommunicator
+	server       *http.Server
+
+	chCommands chan []byte
+}
+
+// Init starts plugin.
+func (w *WebhookAPI) Init(data *api.InitDataAPI) error {
+	w.communicator = data.Communicator
+	w.isMaster = data.IsMaster
+	w.logger = data.Logger
+
+	if data.IsMaster {
+		return w.initMaster()
+	}
+
+	return w.initWorker()
+}
+
+// Routes returns nothing, since webhooks are served by worker.
+func (w *WebhookAPI) Routes() []string {
+	return []string{}
+}
+
+// Unload stops internal processing cycles.
+//noinspection GoUnhandledErrorResult
+func (w *WebhookAPI) Unload() {
+	close(w.chCommands)
+
+	if w.server != nil {
+		w.server.Close() // nolint: gosec, errcheck
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/helpers"
+)
+
+// Init plugin on worker node.
+func (w *WebhookAPI) initWorker() error {
+	parser := helpers.NewParser()
+	for _, v := range w.Settings.Routes {
+		if "" == v.Attributes {
+			continue
+		}
+
+		expr, err := parser.Compile(v.Attributes)
+		if err != nil {
+			return errors.Wrapf(err, "attributes template compile failed for %s", v.Path)
+		}
+
+		v.expression = expr
+	}
+
+	l, err := net.Listen("tcp4", w.Settings.Address)
+	if err != nil {
+		return errors.Wrap(err, "tcp bind failed")
+	}
+
+	w.server = &http.Server{
+		Handler: http.HandlerFunc(w.handleWebhook),
+	}
+
+	go func() {
+		err := w.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			w.logger.Error("Webhooks server failed", err)
+		}
+	}()
+
+	w.logger.Info("Started webhooks server", common.LogDeviceHostToken, w.Settings.Address)
+	return nil
+}
+
+// Handles incoming webhook: validates secret, renders attributes
+// and sends command to master.
+//noinspection GoUnhandledErrorResult
+func (w *WebhookAPI) handleWebhook(rw http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close() // nolint: errcheck
+
+	route, ok := w.Settings.routes[r.URL.Path]
+	if !ok {
+		w.sendJSON(rw, http.StatusNotFound, &ErrorResponse{Error: "unknown webhook"})
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		w.sendJSON(rw, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	if !isValidSecret(r, route.Secret) {
+		w.logger.Warn("Received webhook with invalid secret", common.LogURLToken, r.URL.Path)
+		w.sendJSON(rw, http.StatusUnauthorized, &ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, w.Settings.MaxBodySize))
+	if err != nil {
+		w.sendJSON(rw, http.StatusRequestEntityTooLarge, &ErrorResponse{Error: "body is too large"})
+		return
+	}
+
+	attributes, err := renderAttributes(route, r, body)
+	if err != nil {
+		w.logger.Error("Failed to render webhook attributes", err, common.LogURLToken, r.URL.Path)
+		w.sendJSON(rw, http.StatusBadRequest, &ErrorResponse{Error: "failed to render attributes"})
+		return
+	}
+
+	w.logger.Debug("Received webhook", common.LogURLToken, r.URL.Path, common.LogIDToken, route.Devices)
+	w.communicator.Publish(&DeviceCommandMessage{
+		DeviceID:   route.Devices,
+		Command:    route.Command,
+		Attributes: attributes,
+	})
+
+	w.sendJSON(rw, http.StatusAccepted, &WebhookResponse{
+		Devices: route.Devices,
+		Command: route.Command.String(),
+	})
+}
+
+// Renders command attributes. Template receives request body, parsed
+// as JSON if possible, and query parameters. Rendered template should be a JSON object.
+func renderAttributes(route *RouteSettings, r *http.Request, body []byte) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{})
+	if nil == route.expression {
+		return attributes, nil
+	}
+
+	var parsed interface{}
+	err := json.Unmarshal(body, &parsed)
+	if err != nil {
+		parsed = string(body)
+	}
+
+	query := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		if "secret" == k {
+			continue
+		}
+
+		query[k] = strings.Join(v, ",")
+	}
+
+	res, err := route.expression.Format(map[string]interface{}{
+		"body":  parsed,
+		"query": query,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "template format failed")
+	}
+
+	err = json.Unmarshal([]byte(res), &attributes)
+	if err != nil {
+		return nil, errors.Wrap(err, "rendered attributes are not a json object")
+	}
+
+	return attributes, nil
+}
+
+// Validates webhook secret. Secret is expected either in
+// X-Webhook-Secret header or in secret query parameter.
+func isValidSecret(r *http.Request, secret string) bool {
+	provided := r.Header.Get("X-Webhook-Secret")
+	if "" == provided {
+		provided = r.URL.Query().Get("secret")
+	}
+
+	return 1 == subtle.ConstantTimeCompare([]byte(provided), []byte(secret))
+}
+
+// Sends JSON response.
+func (w *WebhookAPI) sendJSON(rw http.ResponseWriter, status int, data interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	err := json.NewEncoder(rw).Encode(data)
+	if err != nil {
+		w.logger.Error("Failed to send response", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+)
+
+// WemoEmulator implements extended API plugin and
+// provides emulated WeMo sockets.
+type WemoEmulator struct {
+	sync.Mutex
+
+	Settings *Settings
+
+	logger       common.ILoggerProvider
+	isMaster     bool
+	communicator api.IExtendedAPICommunicator
+
+	devices         map[string]*DeviceUpdateMessage
+	rejectedDevices map[string]bool
+	sockets         map[string]*virtualSocket
+	busyPorts       map[int]bool
+	upnp            *discoverUPNP
+
+	chCommands chan []byte
+}
+
+// Init starts plugin.
+func (e *WemoEmulator) Init(data *api.InitDataAPI) error {
+	e.communicator = data.Communicator
+	e.isMaster = data.IsMaster
+	e.logger = data.Logger
+
+	if data.IsMaster {
+		return e.initMaster(data)
+	}
+
+	return e.initWorker(data)
+}
+
+// Routes returns nothing, since we're not exposing anything
+// user-related.
+func (e *WemoEmulator) Routes() []string {
+	return []string{}
+}
+
+// Unload stops internal processing cycles.
+func (e *WemoEmulator) Unload() {
+	close(e.chCommands)
+
+	if !e.isMaster {
+		e.stopSockets()
+	}
+}
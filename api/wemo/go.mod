@@ -0,0 +1,13 @@
+module go-home.io/x/providers/api/wemo
+
+require (
+	github.com/gobwas/glob v0.2.3
+	github.com/julienschmidt/httprouter v0.0.0-20150421170007-8c199fb6259f
+	github.com/pkg/errors v0.8.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+	golang.org/x/net v0.0.0-20180824045131-faa378e6dbae
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+go 1.13
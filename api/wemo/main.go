@@ -0,0 +1,20 @@
+// Package main contains Belkin WeMo emulator implementation for the go-home extended API.
+// Every exposed device is emulated as a separate WeMo socket with its own HTTP port,
+// the same way fauxmo does, since most consumers are ignoring URL paths.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &WemoEmulator{
+			Settings:        settings,
+			devices:         make(map[string]*DeviceUpdateMessage),
+			rejectedDevices: make(map[string]bool),
+			sockets:         make(map[string]*virtualSocket),
+			busyPorts:       make(map[int]bool),
+			chCommands:      make(chan []byte, 5),
+		},
+		settings, nil
+}
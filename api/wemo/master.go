@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// Init plugin on master node.
+func (e *WemoEmulator) initMaster(data *api.InitDataAPI) error {
+	_, chUpdate := data.FanOut.SubscribeDeviceUpdates()
+	err := e.communicator.Subscribe(e.chCommands)
+	if err != nil {
+		return errors.Wrap(err, "subscription failed")
+	}
+	go e.masterCycle(chUpdate, e.chCommands)
+	return nil
+}
+
+// Master internal cycle. Waits for incoming messages
+// either from FanOut or from worker.
+func (e *WemoEmulator) masterCycle(devUpdates chan *common.MsgDeviceUpdate, devCommands chan []byte) {
+	for {
+		select {
+		case update := <-devUpdates:
+			go e.processIncomingDeviceUpdate(update)
+		case cmd, ok := <-devCommands:
+			if !ok {
+				return
+			}
+			go e.processDeviceCommands(cmd)
+		}
+	}
+}
+
+// Processes messages received from worker.
+// If message is marked as discovery -- it's first load on a worker.
+// We need to send all known devices states.
+func (e *WemoEmulator) processDeviceCommands(msg []byte) {
+	cmd := &DeviceCommandMessage{}
+	err := json.Unmarshal(msg, cmd)
+	if err != nil {
+		e.logger.Error("Received corrupted message", err)
+		return
+	}
+
+	if cmd.IsDiscovery {
+		e.Lock()
+		devices := make([]*DeviceUpdateMessage, 0, len(e.devices))
+		for _, v := range e.devices {
+			device := *v
+			devices = append(devices, &device)
+		}
+		e.Unlock()
+
+		for _, v := range devices {
+			e.communicator.Publish(v)
+		}
+
+		return
+	}
+
+	if cmd.Command != enums.CmdOn && cmd.Command != enums.CmdOff {
+		e.logger.Warn("Received unsupported command", common.LogIDToken, cmd.DeviceID)
+		return
+	}
+
+	g, err := glob.Compile(glob.QuoteMeta(cmd.DeviceID))
+	if err != nil {
+		e.logger.Error("Failed to compile device regexp", err)
+		return
+	}
+
+	e.communicator.InvokeDeviceCommand(g, cmd.Command, make(map[string]interface{}))
+}
+
+// Processes FanOut device updates, converts them to worker msg
+// and send through service bus. Workers are notified only
+// if device name or on/off state were changed.
+func (e *WemoEmulator) processIncomingDeviceUpdate(msg *common.MsgDeviceUpdate) {
+	e.Lock()
+	out := e.updateDevice(msg)
+	e.Unlock()
+
+	if out != nil {
+		e.communicator.Publish(out)
+	}
+}
+
+// Applies device update and returns a copy of the changed device.
+// Returns nil if workers should not be notified.
+// Should be called under emulator lock.
+func (e *WemoEmulator) updateDevice(msg *common.MsgDeviceUpdate) *DeviceUpdateMessage {
+	if e.rejectedDevices[msg.ID] {
+		return nil
+	}
+
+	out, ok := e.devices[msg.ID]
+	if !ok {
+		if !e.isMatch(msg) {
+			e.rejectedDevices[msg.ID] = true
+			return nil
+		}
+
+		out = &DeviceUpdateMessage{
+			DeviceID: msg.ID,
+		}
+
+		out.Name = e.getDeviceName(out)
+		e.devices[msg.ID] = out
+	}
+
+	wasUpdated := !ok
+	if "" != msg.Name && out.originalName != msg.Name {
+		out.originalName = msg.Name
+		out.Name = e.getDeviceName(out)
+		wasUpdated = true
+	}
+
+	if on, ok := msg.State[enums.PropOn]; ok {
+		isOn, _ := on.(bool) // nolint: gosec
+		if isOn != out.IsOn {
+			out.IsOn = isOn
+			wasUpdated = true
+		}
+	}
+
+	if !wasUpdated {
+		return nil
+	}
+
+	device := *out
+	return &device
+}
+
+// Validates whether device matches filter regexps and its type is supported.
+func (e *WemoEmulator) isMatch(msg *common.MsgDeviceUpdate) bool {
+	if !enums.SliceContainsDeviceType(e.Settings.types, msg.Type) {
+		return false
+	}
+
+	for _, v := range e.Settings.devRegexp {
+		if v.Match(msg.ID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns either overwritten name or uses device name.
+func (e *WemoEmulator) getDeviceName(msg *DeviceUpdateMessage) string {
+	name, ok := e.Settings.NamesOverrides[msg.DeviceID]
+	if ok {
+		return name
+	}
+
+	if "" == msg.originalName {
+		return msg.DeviceID
+	}
+
+	return msg.originalName
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+// DeviceUpdateMessage has data about device update.
+// This message is produced by master.
+type DeviceUpdateMessage struct {
+	api.ExtendedAPIMessage
+	Name     string `json:"n"`
+	DeviceID string `json:"i"`
+	IsOn     bool   `json:"o"`
+
+	originalName string
+}
+
+// DeviceCommandMessage has data with new device command.
+// This message is produced by worker.
+type DeviceCommandMessage struct {
+	api.ExtendedAPIMessage
+	IsDiscovery bool          `json:"d"`
+	DeviceID    string        `json:"i"`
+	Command     enums.Command `json:"c"`
+}
+
+// Describes SOAP request sent to basicevent1 service.
+type soapRequest struct {
+	Body struct {
+		SetBinaryState *struct {
+			BinaryState string `xml:"BinaryState"`
+		} `xml:"SetBinaryState"`
+		GetBinaryState *struct{} `xml:"GetBinaryState"`
+	} `xml:"Body"`
+}
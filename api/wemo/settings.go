@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+const (
+	// Describes maximum possible TCP port.
+	portMax = 65535
+)
+
+// Settings has data required to start API.
+type Settings struct {
+	AdvAddress     string             `yaml:"advAddress" validate:"required,ipv4"`
+	PortStart      int                `yaml:"portStart" validate:"gt=0,lte=65535" default:"49153"`
+	Ports          map[string]int     `yaml:"ports"`
+	Interface      string             `yaml:"interface"`
+	DeviceFilter   []string           `yaml:"devices"`
+	DeviceTypes    []enums.DeviceType `yaml:"types"`
+	NamesOverrides map[string]string  `yaml:"nameOverrides"`
+
+	devRegexp []glob.Glob
+	types     []enums.DeviceType
+}
+
+// Validate performs config validation.
+func (s *Settings) Validate() error {
+	s.types = make([]enums.DeviceType, 0)
+	for _, v := range s.DeviceTypes {
+		if !enums.SliceContainsDeviceType(supportedTypes, v) {
+			continue
+		}
+
+		s.types = append(s.types, v)
+	}
+
+	if 0 == len(s.types) {
+		s.types = supportedTypes
+	}
+
+	usedPorts := make(map[int]string)
+	for k, v := range s.Ports {
+		if v <= 0 || v > portMax {
+			return errors.Errorf("port for %s is out of range", k)
+		}
+
+		if d, ok := usedPorts[v]; ok {
+			return errors.Errorf("port %d is used by both %s and %s", v, d, k)
+		}
+
+		usedPorts[v] = k
+	}
+
+	if 0 == len(s.DeviceFilter) {
+		s.DeviceFilter = []string{"**"}
+	}
+
+	s.devRegexp = make([]glob.Glob, 0)
+	for _, v := range s.DeviceFilter {
+		a, err := glob.Compile(v)
+		if err != nil {
+			return errors.Wrap(err, "glob compile failed")
+		}
+
+		s.devRegexp = append(s.devRegexp, a)
+	}
+
+	return nil
+}
+
+// List of supported device types.
+var supportedTypes = []enums.DeviceType{enums.DevLight, enums.DevSwitch, enums.DevGroup}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/sha1" // nolint: gosec
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes WeMo socket device type.
+	wemoDeviceType = "urn:Belkin:device:controllee:1"
+	// Describes WeMo basic event service type.
+	wemoServiceType = "urn:Belkin:service:basicevent:1"
+	// Describes SOAP response template for basic event service.
+	soapResponse = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>` +
+		`<u:%[1]sResponse xmlns:u="urn:Belkin:service:basicevent:1">` +
+		`<BinaryState>%[2]d</BinaryState>` +
+		`</u:%[1]sResponse></s:Body></s:Envelope>`
+	// Describes basic event service description.
+	eventServiceXML = `<?xml version="1.0"?>
+<scpd xmlns="urn:Belkin:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>SetBinaryState</name>
+      <argumentList>
+        <argument>
+          <retval/>
+          <name>BinaryState</name>
+          <relatedStateVariable>BinaryState</relatedStateVariable>
+          <direction>in</direction>
+        </argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetBinaryState</name>
+      <argumentList>
+        <argument>
+          <retval/>
+          <name>BinaryState</name>
+          <relatedStateVariable>BinaryState</relatedStateVariable>
+          <direction>out</direction>
+        </argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes">
+      <name>BinaryState</name>
+      <dataType>Boolean</dataType>
+      <defaultValue>0</defaultValue>
+    </stateVariable>
+  </serviceStateTable>
+</scpd>
+`
+)
+
+// Emulated WeMo socket. Every socket listens on its own port.
+type virtualSocket struct {
+	sync.Mutex
+
+	emulator *WemoEmulator
+	logger   common.ILoggerProvider
+	listener net.Listener
+
+	deviceID     string
+	name         string
+	isOn         bool
+	serialNumber string
+	port         int
+}
+
+// Constructs a new virtual socket. Serial number is based on the device ID,
+// so consumers see the same socket after restart.
+func newVirtualSocket(e *WemoEmulator, update *DeviceUpdateMessage, port int) *virtualSocket {
+	h := sha1.Sum([]byte(update.DeviceID)) // nolint: gosec
+	return &virtualSocket{
+		emulator:     e,
+		logger:       e.logger,
+		deviceID:     update.DeviceID,
+		name:         update.Name,
+		isOn:         update.IsOn,
+		serialNumber: strings.ToUpper(hex.EncodeToString(h[:7])),
+		port:         port,
+	}
+}
+
+// Start binds socket HTTP API.
+func (s *virtualSocket) Start() error {
+	l, err := net.Listen("tcp4", fmt.Sprintf("0.0.0.0:%d", s.port))
+	if err != nil {
+		return errors.Wrap(err, "tcp bind failed")
+	}
+
+	s.listener = l
+
+	router := httprouter.New()
+	router.GET("/setup.xml", s.setup)
+	router.GET("/eventservice.xml", s.eventService)
+	router.POST("/upnp/control/basicevent1", s.control)
+
+	go func() {
+		err := http.Serve(s.listener, router)
+		if err != nil {
+			return
+		}
+	}()
+
+	s.logger.Info("Started WeMo socket", common.LogIDToken, s.deviceID, "port", fmt.Sprintf("%d", s.port))
+	return nil
+}
+
+// Stop stops socket HTTP API.
+//noinspection GoUnhandledErrorResult
+func (s *virtualSocket) Stop() {
+	if s.listener != nil {
+		s.listener.Close() // nolint: gosec, errcheck
+	}
+}
+
+// Update applies device state received from master.
+func (s *virtualSocket) Update(update *DeviceUpdateMessage) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.name = update.Name
+	s.isOn = update.IsOn
+}
+
+// Returns socket unique device name.
+func (s *virtualSocket) getUDN() string {
+	return "uuid:Socket-1_0-" + s.serialNumber
+}
+
+// Returns socket setup URL.
+func (s *virtualSocket) getLocation() string {
+	return fmt.Sprintf("http://%s:%d/setup.xml", s.emulator.Settings.AdvAddress, s.port)
+}
+
+// Returns current binary state.
+func (s *virtualSocket) getBinaryState() int {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.isOn {
+		return 1
+	}
+
+	return 0
+}
+
+// Replies on initial HTTP discovery request
+// with socket details in XML format.
+func (s *virtualSocket) setup(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	type Service struct {
+		ServiceType string `xml:"serviceType"`
+		ServiceID   string `xml:"serviceId"`
+		ControlURL  string `xml:"controlURL"`
+		EventSubURL string `xml:"eventSubURL"`
+		SCPDURL     string `xml:"SCPDURL"`
+	}
+
+	type Root struct {
+		XMLName struct{} `xml:"urn:Belkin:device-1-0 root"`
+
+		Major int `xml:"specVersion>major"`
+		Minor int `xml:"specVersion>minor"`
+
+		DeviceType       string    `xml:"device>deviceType"`
+		FriendlyName     string    `xml:"device>friendlyName"`
+		Manufacturer     string    `xml:"device>manufacturer"`
+		ModelName        string    `xml:"device>modelName"`
+		ModelNumber      string    `xml:"device>modelNumber"`
+		ModelDescription string    `xml:"device>modelDescription"`
+		UDN              string    `xml:"device>UDN"`
+		SerialNumber     string    `xml:"device>serialNumber"`
+		BinaryState      int       `xml:"device>binaryState"`
+		Services         []Service `xml:"device>serviceList>service"`
+	}
+
+	s.Lock()
+	name := s.name
+	s.Unlock()
+
+	x := Root{
+		Major: 1,
+		Minor: 0,
+
+		DeviceType:       wemoDeviceType,
+		FriendlyName:     name,
+		Manufacturer:     "Belkin International Inc.",
+		ModelName:        "Socket",
+		ModelNumber:      "3.1415",
+		ModelDescription: "Belkin Plugin Socket 1.0",
+		UDN:              s.getUDN(),
+		SerialNumber:     s.serialNumber,
+		BinaryState:      s.getBinaryState(),
+		Services: []Service{{
+			ServiceType: wemoServiceType,
+			ServiceID:   "urn:Belkin:serviceId:basicevent1",
+			ControlURL:  "/upnp/control/basicevent1",
+			EventSubURL: "/upnp/event/basicevent1",
+			SCPDURL:     "/eventservice.xml",
+		}},
+	}
+
+	s.logger.Debug("WeMo setup is requested", common.LogIDToken, s.deviceID)
+
+	w.Header().Set("Content-Type", "text/xml")
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		s.logger.Error("Error writing xml header", err)
+	}
+	err = xml.NewEncoder(w).Encode(&x)
+	if err != nil {
+		s.logger.Error("Encoder error", err)
+	}
+}
+
+// Replies with basic event service description.
+func (s *virtualSocket) eventService(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "text/xml")
+	_, err := io.WriteString(w, eventServiceXML)
+	if err != nil {
+		s.logger.Error("Error writing event service xml", err)
+	}
+}
+
+// Responds to basic event SOAP requests. SetBinaryState sends
+// command to master and optimistically updates socket state.
+//noinspection GoUnhandledErrorResult
+func (s *virtualSocket) control(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	defer r.Body.Close() // nolint: errcheck
+	req := &soapRequest{}
+	err := xml.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		s.logger.Error("Failed to decode SOAP request", err, common.LogIDToken, s.deviceID)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	action := "GetBinaryState"
+	switch {
+	case req.Body.SetBinaryState != nil:
+		action = "SetBinaryState"
+		isOn := "0" != strings.TrimSpace(req.Body.SetBinaryState.BinaryState)
+
+		s.Lock()
+		s.isOn = isOn
+		s.Unlock()
+
+		s.logger.Debug("Requested WeMo state change", common.LogIDToken, s.deviceID)
+		s.emulator.sendCommand(s.deviceID, isOn)
+	case req.Body.GetBinaryState != nil:
+	default:
+		s.logger.Warn("Received unsupported SOAP action", common.LogIDToken, s.deviceID,
+			"action", r.Header.Get("SOAPACTION"))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, err = fmt.Fprintf(w, soapResponse, action, s.getBinaryState())
+	if err != nil {
+		s.logger.Error("Error writing SOAP response", err)
+	}
+}
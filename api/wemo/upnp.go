@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// Describes SSDP search target for all devices.
+	ssdpAll = "ssdp:all"
+	// Describes SSDP search target for root devices.
+	upnpRootDevice = "upnp:rootdevice"
+	// Describes SSDP search target for all Belkin devices.
+	belkinAll = "urn:Belkin:device:**"
+)
+
+// Discovery provider. Responds on behalf of every started socket.
+type discoverUPNP struct {
+	logger   common.ILoggerProvider
+	emulator *WemoEmulator
+	iface    string
+
+	connection *net.UDPConn
+}
+
+// Start starts new UPNP server.
+// Multicast listener sets SO_REUSEADDR, so port is shared with
+// other SSDP servers, e.g. HUE emulator.
+func (d *discoverUPNP) Start() error {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		d.logger.Error("No available interfaces", err)
+		return errors.Wrap(err, "no interface available")
+	}
+
+	var listenIface *net.Interface
+	if "" != d.iface {
+		for _, v := range interfaces {
+			v := v
+			if v.Name == d.iface {
+				listenIface = &v
+				break
+			}
+		}
+
+		if nil == listenIface {
+			err = errors.New("interface not found")
+			d.logger.Error("Failed to start UPNP", err, "interface", d.iface)
+			return err
+		}
+	}
+
+	addr := &net.UDPAddr{
+		IP:   net.IPv4(239, 255, 255, 250),
+		Port: 1900,
+	}
+
+	l, err := net.ListenMulticastUDP("udp4", listenIface, addr)
+	if err != nil {
+		d.logger.Error("Failed to start UPNP server", err)
+		return errors.Wrap(err, "upnp start failed")
+	}
+
+	d.connection = l
+	joined := []string{d.iface}
+	if nil == listenIface {
+		joined = d.joinInterfaces(interfaces, addr)
+	}
+
+	d.logger.Debug("Started UPNP server", "addresses", strings.Join(joined, " "))
+
+	go d.listen()
+	return nil
+}
+
+// Joins multicast group on every available interface.
+// Default interface is already joined by the listener.
+func (d *discoverUPNP) joinInterfaces(interfaces []net.Interface, addr *net.UDPAddr) []string {
+	p := ipv4.NewPacketConn(d.connection)
+	joined := []string{"default"}
+	for _, v := range interfaces {
+		v := v
+		if v.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+
+		err := p.JoinGroup(&v, addr)
+		if err != nil {
+			continue
+		}
+
+		joined = append(joined, v.Name)
+	}
+
+	return joined
+}
+
+// Stop stops running UPNP server.
+//noinspection GoUnhandledErrorResult
+func (d *discoverUPNP) Stop() {
+	if d.connection != nil {
+		d.connection.Close() // nolint: gosec, errcheck
+	}
+}
+
+// Waits for incoming UDP messages.
+func (d *discoverUPNP) listen() {
+	var b [1500]byte
+	for {
+		n, add, err := d.connection.ReadFromUDP(b[:])
+		if err != nil {
+			d.logger.Debug("UPNP server stopped", "reason", err.Error())
+			return
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b[:n])))
+		if err != nil {
+			continue
+		}
+
+		if req.Method != "M-SEARCH" || req.URL.Path != "*" ||
+			req.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+
+		st := getSearchTarget(req.Header.Get("St"))
+		if "" == st {
+			continue
+		}
+
+		d.logger.Debug("Received discovery request", "address", add.String())
+		for _, v := range d.emulator.getSockets() {
+			d.discoveryRespond(add, st, v)
+		}
+	}
+}
+
+// Returns search target used in the response
+// or empty string if sockets shouldn't respond.
+func getSearchTarget(st string) string {
+	switch st {
+	case ssdpAll, belkinAll:
+		return wemoDeviceType
+	case upnpRootDevice, wemoDeviceType, wemoServiceType:
+		return st
+	}
+
+	return ""
+}
+
+// Responds to discovery message with socket location.
+//noinspection GoUnhandledErrorResult
+func (d *discoverUPNP) discoveryRespond(addr *net.UDPAddr, st string, s *virtualSocket) {
+	c, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		d.logger.Error("Discovery respond error", err)
+		return
+	}
+	defer c.Close() // nolint: errcheck
+
+	var buf bytes.Buffer
+	_, err = buf.WriteString("HTTP/1.1 200 OK\r\n")
+	if err != nil {
+		d.logger.Error("Error writing UPnP http", err)
+		return
+	}
+
+	err = http.Header{
+		"Cache-Control": {`max-age=86400`},
+		"Ext":           {``},
+		"Location":      {s.getLocation()},
+		"Opt":           {`"http://schemas.upnp.org/upnp/1/0/"; ns=01`},
+		"01-Nls":        {s.serialNumber},
+		"Server":        {`Unspecified, UPnP/1.0, Unspecified`},
+		"X-User-Agent":  {`redsonic`},
+		"St":            {st},
+		"Usn":           {s.getUDN() + "::" + st},
+	}.Write(&buf)
+	if err != nil {
+		d.logger.Error("Error writing UPnP headers", err)
+		return
+	}
+
+	_, err = buf.WriteString("\r\n")
+	if err != nil {
+		d.logger.Error("Error writing UPnP finish", err)
+		return
+	}
+
+	_, err = c.Write(buf.Bytes())
+	if err != nil {
+		d.logger.Error("Error writing UPnP response", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/api"
+	"go-home.io/x/server/plugins/common"
+	"go-home.io/x/server/plugins/device/enums"
+)
+
+const (
+	// Describes how many ports are tried before socket start is given up.
+	maxPortAttempts = 10
+)
+
+// Init plugin on worker node.
+func (e *WemoEmulator) initWorker(_ *api.InitDataAPI) error {
+	e.upnp = &discoverUPNP{
+		logger:   e.logger,
+		emulator: e,
+		iface:    e.Settings.Interface,
+	}
+
+	err := e.upnp.Start()
+	if err != nil {
+		return errors.Wrap(err, "upnp start failed")
+	}
+
+	err = e.communicator.Subscribe(e.chCommands)
+	if err != nil {
+		e.upnp.Stop()
+		return errors.Wrap(err, "bus subscription failed")
+	}
+
+	go e.workerCycle(e.chCommands)
+	e.communicator.Publish(&DeviceCommandMessage{
+		IsDiscovery: true,
+	})
+	return nil
+}
+
+// Stops all started sockets and UPNP discovery.
+func (e *WemoEmulator) stopSockets() {
+	e.Lock()
+	defer e.Unlock()
+
+	for _, v := range e.sockets {
+		v.Stop()
+	}
+
+	if e.upnp != nil {
+		e.upnp.Stop()
+	}
+}
+
+// Worker internal bus cycle. Waits for incoming devices updates.
+func (e *WemoEmulator) workerCycle(devUpdates chan []byte) {
+	for msg := range devUpdates {
+		go e.processDeviceUpdate(msg)
+	}
+}
+
+// Processes incoming device update message.
+// A new socket is started for every new device.
+func (e *WemoEmulator) processDeviceUpdate(msg []byte) {
+	e.Lock()
+	defer e.Unlock()
+
+	update := &DeviceUpdateMessage{}
+	err := json.Unmarshal(msg, update)
+	if err != nil {
+		e.logger.Error("Received corrupted message from master", err)
+		return
+	}
+
+	s, ok := e.sockets[update.DeviceID]
+	if ok {
+		s.Update(update)
+		return
+	}
+
+	_, isPinned := e.Settings.Ports[update.DeviceID]
+	for attempt := 1; ; attempt++ {
+		port := e.allocatePort(update.DeviceID)
+		s = newVirtualSocket(e, update, port)
+		err = s.Start()
+		if nil == err {
+			break
+		}
+
+		e.logger.Error("Failed to start WeMo socket", err, common.LogIDToken, update.DeviceID,
+			"port", strconv.Itoa(port))
+		if isPinned || attempt >= maxPortAttempts {
+			return
+		}
+
+		e.busyPorts[port] = true
+	}
+
+	e.sockets[update.DeviceID] = s
+}
+
+// Returns pinned port for the device or the first unused one.
+// Ports which failed to bind are skipped.
+// Should be called under emulator lock.
+func (e *WemoEmulator) allocatePort(deviceID string) int {
+	if port, ok := e.Settings.Ports[deviceID]; ok {
+		return port
+	}
+
+	used := make(map[int]bool)
+	for k := range e.busyPorts {
+		used[k] = true
+	}
+
+	for _, v := range e.Settings.Ports {
+		used[v] = true
+	}
+
+	for _, v := range e.sockets {
+		used[v.port] = true
+	}
+
+	port := e.Settings.PortStart
+	for used[port] {
+		port++
+	}
+
+	return port
+}
+
+// Returns all started sockets.
+func (e *WemoEmulator) getSockets() []*virtualSocket {
+	e.Lock()
+	defer e.Unlock()
+
+	sockets := make([]*virtualSocket, 0, len(e.sockets))
+	for _, v := range e.sockets {
+		sockets = append(sockets, v)
+	}
+
+	return sockets
+}
+
+// Sends on/off command to master.
+func (e *WemoEmulator) sendCommand(deviceID string, isOn bool) {
+	cmd := &DeviceCommandMessage{
+		DeviceID: deviceID,
+		Command:  enums.CmdOff,
+	}
+
+	if isOn {
+		cmd.Command = enums.CmdOn
+	}
+
+	e.communicator.Publish(cmd)
+}
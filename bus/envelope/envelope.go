@@ -0,0 +1,227 @@
+// Package envelope contains message envelope shared by service bus providers.
+// Envelope adds message ID, sender node, schema version, timestamp, trace ID
+// and HMAC signature to the bus message.
+package envelope
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// SchemaVersion describes the latest supported envelope version.
+const SchemaVersion = 1
+
+var (
+	// ErrNotWrapped describes message without envelope.
+	ErrNotWrapped = errors.New("message is not wrapped")
+	// ErrUnsupportedVersion describes envelope of the newer version.
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+	// ErrNotSigned describes message without signature.
+	ErrNotSigned = errors.New("message is not signed")
+	// ErrInvalidSignature describes message with wrong signature.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpired describes message which is older than max age.
+	ErrExpired = errors.New("message is expired")
+	// ErrReplayed describes message which was already received.
+	ErrReplayed = errors.New("message is replayed")
+)
+
+// ITracedMessage defines message which carries own trace ID.
+type ITracedMessage interface {
+	TraceID() string
+}
+
+// Envelope describes wrapped bus message.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Sender    string          `json:"sender"`
+	Version   int             `json:"v"`
+	Timestamp int64           `json:"ts"`
+	TraceID   string          `json:"trace"`
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"sig,omitempty"`
+}
+
+// Codec wraps outgoing messages and validates incoming ones.
+// Disabled codec sends bare JSON.
+type Codec struct {
+	settings *Settings
+	nodeID   string
+	key      []byte
+	seen     *replayCache
+}
+
+// New creates a new codec. Nil settings produce disabled codec.
+func New(settings *Settings, nodeID string, secret common.ISecretProvider) (*Codec, error) {
+	c := &Codec{
+		settings: settings,
+		nodeID:   nodeID,
+	}
+
+	if nil == settings || !settings.Enabled {
+		return c, nil
+	}
+
+	if "" == settings.KeySecret {
+		return nil, errors.New("signing key secret is required")
+	}
+
+	if nil == secret {
+		return nil, errors.New("secret provider is not available")
+	}
+
+	key, err := secret.Get(settings.KeySecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "key secret get failed")
+	}
+
+	if "" == key {
+		return nil, errors.New("signing key is empty")
+	}
+
+	c.key = []byte(key)
+
+	c.seen = newReplayCache(c.maxAge())
+	return c, nil
+}
+
+// Prepared describes message payload which is not sealed yet.
+// Envelope is created at send time, so re-sent messages are not expired.
+type Prepared struct {
+	Payload []byte
+	TraceID string
+}
+
+// Marshal converts message into JSON and wraps it into the envelope.
+func (c *Codec) Marshal(message interface{}) ([]byte, error) {
+	p, err := c.Prepare(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Seal(p)
+}
+
+// Prepare converts message into JSON and picks its trace ID.
+func (c *Codec) Prepare(message interface{}) (*Prepared, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Prepared{Payload: payload}
+	if t, ok := message.(ITracedMessage); ok {
+		p.TraceID = t.TraceID()
+	}
+
+	return p, nil
+}
+
+// Seal wraps prepared payload into a new envelope.
+func (c *Codec) Seal(p *Prepared) ([]byte, error) {
+	if !c.isEnabled() {
+		return p.Payload, nil
+	}
+
+	e := &Envelope{
+		ID:        newID(),
+		Sender:    c.nodeID,
+		Version:   SchemaVersion,
+		Timestamp: time.Now().UnixNano(),
+		TraceID:   p.TraceID,
+		Payload:   p.Payload,
+	}
+
+	if "" == e.TraceID {
+		e.TraceID = newID()
+	}
+
+	e.Signature = c.sign(e)
+	return json.Marshal(e)
+}
+
+// Open validates incoming message and returns its payload.
+// Unwrapped, unsigned, expired or replayed messages are rejected if envelope is enabled.
+func (c *Codec) Open(data []byte) ([]byte, *Envelope, error) {
+	if !c.isEnabled() {
+		return data, nil, nil
+	}
+
+	e := &Envelope{}
+	err := json.Unmarshal(data, e)
+	if err != nil || 0 == e.Version || nil == e.Payload {
+		return nil, nil, ErrNotWrapped
+	}
+
+	if e.Version > SchemaVersion {
+		return nil, e, errors.Wrap(ErrUnsupportedVersion, strconv.Itoa(e.Version))
+	}
+
+	if "" == e.Signature {
+		return nil, e, ErrNotSigned
+	}
+
+	if !hmac.Equal([]byte(e.Signature), []byte(c.sign(e))) {
+		return nil, e, ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(0, e.Timestamp))
+	if age > c.maxAge() || age < -c.maxAge() {
+		return nil, e, ErrExpired
+	}
+
+	if !c.seen.Add(e.ID, time.Unix(0, e.Timestamp)) {
+		return nil, e, ErrReplayed
+	}
+
+	return e.Payload, e, nil
+}
+
+// Forget allows the message with ID to be received again.
+// Used when message is returned to the bus for re-delivery.
+func (c *Codec) Forget(id string) {
+	if !c.isEnabled() {
+		return
+	}
+
+	c.seen.Remove(id)
+}
+
+// Returns HMAC signature of the envelope fields.
+func (c *Codec) sign(e *Envelope) string {
+	mac := hmac.New(sha256.New, c.key)
+	for _, v := range []string{e.ID, e.Sender, strconv.Itoa(e.Version),
+		strconv.FormatInt(e.Timestamp, 10), e.TraceID} {
+		mac.Write([]byte(v)) // nolint: gosec, errcheck
+		mac.Write([]byte{0}) // nolint: gosec, errcheck
+	}
+
+	mac.Write(e.Payload) // nolint: gosec, errcheck
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Checks whether envelope is used.
+func (c *Codec) isEnabled() bool {
+	return c.settings != nil && c.settings.Enabled
+}
+
+// Returns max allowed message age.
+func (c *Codec) maxAge() time.Duration {
+	return time.Duration(c.settings.MaxAge) * time.Second
+}
+
+// Returns random message ID.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b) // nolint: gosec, errcheck
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,289 @@
+package envelope
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Fake secret provider.
+type fakeSecret map[string]string
+
+func (s fakeSecret) Get(name string) (string, error) {
+	v, ok := s[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+
+	return v, nil
+}
+
+func (s fakeSecret) Set(name string, value string) error {
+	s[name] = value
+	return nil
+}
+
+// Returns a new signing codec.
+func getCodec(t *testing.T, key string) *Codec {
+	c, err := New(&Settings{Enabled: true, KeySecret: "key", MaxAge: 60}, "node", fakeSecret{"key": key})
+	if err != nil {
+		t.Fatalf("codec init failed: %s", err.Error())
+	}
+
+	return c
+}
+
+// Re-signs modified envelope.
+func reseal(t *testing.T, c *Codec, data []byte, modify func(*Envelope)) []byte {
+	e := &Envelope{}
+	err := json.Unmarshal(data, e)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %s", err.Error())
+	}
+
+	modify(e)
+	e.Signature = c.sign(e)
+	res, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err.Error())
+	}
+
+	return res
+}
+
+// Tests envelope validation.
+func TestOpen(t *testing.T) {
+	data := []struct {
+		name    string
+		prepare func(*testing.T, *Codec, []byte) []byte
+		opener  func(*testing.T, *Codec) *Codec
+		err     error
+	}{
+		{
+			name: "round trip",
+		},
+		{
+			name: "wrong key",
+			opener: func(t *testing.T, c *Codec) *Codec {
+				return getCodec(t, "another key")
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "tampered payload",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				e := &Envelope{}
+				json.Unmarshal(data, e) // nolint: gosec, errcheck
+				e.Payload = json.RawMessage(`{"value":"tampered"}`)
+				res, _ := json.Marshal(e) // nolint: gosec
+				return res
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "not signed",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				e := &Envelope{}
+				json.Unmarshal(data, e) // nolint: gosec, errcheck
+				e.Signature = ""
+				res, _ := json.Marshal(e) // nolint: gosec
+				return res
+			},
+			err: ErrNotSigned,
+		},
+		{
+			name: "not wrapped",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				return []byte(`{"value":"test"}`)
+			},
+			err: ErrNotWrapped,
+		},
+		{
+			name: "expired",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				return reseal(t, c, data, func(e *Envelope) {
+					e.Timestamp = time.Now().Add(-2 * time.Minute).UnixNano()
+				})
+			},
+			err: ErrExpired,
+		},
+		{
+			name: "from the future",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				return reseal(t, c, data, func(e *Envelope) {
+					e.Timestamp = time.Now().Add(2 * time.Minute).UnixNano()
+				})
+			},
+			err: ErrExpired,
+		},
+		{
+			name: "newer version",
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				return reseal(t, c, data, func(e *Envelope) {
+					e.Version = SchemaVersion + 1
+				})
+			},
+			err: ErrUnsupportedVersion,
+		},
+		{
+			name: "replayed",
+			opener: func(t *testing.T, c *Codec) *Codec {
+				return c
+			},
+			prepare: func(t *testing.T, c *Codec, data []byte) []byte {
+				_, _, err := c.Open(data)
+				if err != nil {
+					t.Fatalf("first delivery failed: %s", err.Error())
+				}
+
+				return data
+			},
+			err: ErrReplayed,
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			c := getCodec(t, "secret")
+			opener := getCodec(t, "secret")
+			if v.opener != nil {
+				opener = v.opener(t, c)
+			}
+
+			msg, err := c.Marshal(map[string]string{"value": "test"})
+			if err != nil {
+				t.Fatalf("marshal failed: %s", err.Error())
+			}
+
+			if v.prepare != nil {
+				msg = v.prepare(t, opener, msg)
+			}
+
+			payload, _, err := opener.Open(msg)
+			if v.err != nil {
+				if errors.Cause(err) != v.err {
+					t.Fatalf("expected %v, got %v", v.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("open failed: %s", err.Error())
+			}
+
+			if `{"value":"test"}` != string(payload) {
+				t.Fatalf("wrong payload: %s", string(payload))
+			}
+		})
+	}
+}
+
+// Tests that forgotten message could be delivered again.
+func TestForget(t *testing.T) {
+	c := getCodec(t, "secret")
+	msg, err := c.Marshal(map[string]string{"value": "test"})
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err.Error())
+	}
+
+	_, e, err := c.Open(msg)
+	if err != nil {
+		t.Fatalf("first delivery failed: %s", err.Error())
+	}
+
+	c.Forget(e.ID)
+	_, _, err = c.Open(msg)
+	if err != nil {
+		t.Fatalf("re-delivery failed: %s", err.Error())
+	}
+
+	_, _, err = c.Open(msg)
+	if errors.Cause(err) != ErrReplayed {
+		t.Fatalf("expected %v, got %v", ErrReplayed, err)
+	}
+}
+
+// Tests that every seal produces a new envelope.
+func TestSeal(t *testing.T) {
+	c := getCodec(t, "secret")
+	p, err := c.Prepare(map[string]string{"value": "test"})
+	if err != nil {
+		t.Fatalf("prepare failed: %s", err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		msg, err := c.Seal(p)
+		if err != nil {
+			t.Fatalf("seal failed: %s", err.Error())
+		}
+
+		_, _, err = c.Open(msg)
+		if err != nil {
+			t.Fatalf("open of seal %d failed: %s", i, err.Error())
+		}
+	}
+}
+
+// Tests that enabled codec requires signing key.
+func TestNew(t *testing.T) {
+	data := []struct {
+		name     string
+		settings *Settings
+		secret   common.ISecretProvider
+	}{
+		{
+			name:     "no key secret",
+			settings: &Settings{Enabled: true, MaxAge: 60},
+			secret:   fakeSecret{"key": "secret"},
+		},
+		{
+			name:     "no secret provider",
+			settings: &Settings{Enabled: true, KeySecret: "key", MaxAge: 60},
+		},
+		{
+			name:     "unknown secret",
+			settings: &Settings{Enabled: true, KeySecret: "unknown", MaxAge: 60},
+			secret:   fakeSecret{"key": "secret"},
+		},
+		{
+			name:     "empty key",
+			settings: &Settings{Enabled: true, KeySecret: "key", MaxAge: 60},
+			secret:   fakeSecret{"key": ""},
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := New(v.settings, "node", v.secret)
+			if nil == err {
+				t.Fatal("expected codec init to fail")
+			}
+		})
+	}
+}
+
+// Tests that disabled codec sends bare JSON.
+func TestDisabled(t *testing.T) {
+	c, err := New(nil, "node", fakeSecret{})
+	if err != nil {
+		t.Fatalf("codec init failed: %s", err.Error())
+	}
+
+	msg, err := c.Marshal(map[string]string{"value": "test"})
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err.Error())
+	}
+
+	if `{"value":"test"}` != string(msg) {
+		t.Fatalf("wrong message: %s", string(msg))
+	}
+
+	payload, e, err := c.Open(msg)
+	if err != nil || e != nil || string(msg) != string(payload) {
+		t.Fatalf("disabled codec changed the message")
+	}
+}
@@ -0,0 +1,63 @@
+package envelope
+
+import (
+	"sync"
+	"time"
+)
+
+// Keeps IDs of received messages within the max age window.
+// Older messages are rejected by timestamp, so they can be forgotten.
+type replayCache struct {
+	sync.Mutex
+
+	maxAge    time.Duration
+	ids       map[string]time.Time
+	lastPrune time.Time
+}
+
+// Creates a new replay cache.
+func newReplayCache(maxAge time.Duration) *replayCache {
+	return &replayCache{
+		maxAge:    maxAge,
+		ids:       make(map[string]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+// Add remembers message ID. Returns false if ID was already seen.
+func (c *replayCache) Add(id string, timestamp time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	c.prune()
+	if _, ok := c.ids[id]; ok {
+		return false
+	}
+
+	c.ids[id] = timestamp
+	return true
+}
+
+// Remove forgets message ID.
+func (c *replayCache) Remove(id string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.ids, id)
+}
+
+// Removes IDs which are older than max age with skew.
+// Should be called under cache lock.
+func (c *replayCache) prune() {
+	if time.Since(c.lastPrune) < c.maxAge {
+		return
+	}
+
+	c.lastPrune = time.Now()
+	deadline := c.lastPrune.Add(-2 * c.maxAge)
+	for k, v := range c.ids {
+		if v.Before(deadline) {
+			delete(c.ids, k)
+		}
+	}
+}
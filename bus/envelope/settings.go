@@ -0,0 +1,9 @@
+package envelope
+
+// Settings describes envelope settings which are embedded into bus providers settings.
+// HMAC key secret is required once envelope is enabled.
+type Settings struct {
+	Enabled   bool   `yaml:"enabled"`
+	KeySecret string `yaml:"keySecret"`
+	MaxAge    int    `yaml:"maxAge" validate:"gt=0" default:"60"`
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+)
+
+// Process-wide messages router shared by all bus instances.
+var defaultBroker = &broker{
+	channels: make(map[string]map[*subscription]bool),
+}
+
+// Routes messages between subscriptions within the process.
+type broker struct {
+	sync.Mutex
+
+	channels map[string]map[*subscription]bool
+}
+
+// Adds channel subscription.
+func (b *broker) add(channel string, sub *subscription) {
+	b.Lock()
+	defer b.Unlock()
+
+	subs, ok := b.channels[channel]
+	if !ok {
+		subs = make(map[*subscription]bool)
+		b.channels[channel] = subs
+	}
+
+	subs[sub] = true
+}
+
+// Removes channel subscription.
+func (b *broker) remove(channel string, sub *subscription) {
+	b.Lock()
+	defer b.Unlock()
+
+	subs, ok := b.channels[channel]
+	if !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if 0 == len(subs) {
+		delete(b.channels, channel)
+	}
+}
+
+// Returns all channel subscriptions.
+func (b *broker) get(channel string) []*subscription {
+	b.Lock()
+	defer b.Unlock()
+
+	result := make([]*subscription, 0, len(b.channels[channel]))
+	for k := range b.channels[channel] {
+		result = append(result, k)
+	}
+
+	return result
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Describes single channel subscription with bounded buffer.
+type subscription struct {
+	buffer   chan bus.RawMessage
+	stopChan chan bool
+}
+
+// MemoryBus describes in-process bus plugin implementation.
+// Messages are delivered only between nodes running in the same process.
+type MemoryBus struct {
+	sync.Mutex
+
+	logger        common.ILoggerProvider
+	Settings      *Settings
+	subscriptions map[string]*subscription
+}
+
+// Init saves logger, no connection is required.
+func (b *MemoryBus) Init(data *bus.InitDataServiceBus) error {
+	b.logger = data.Logger
+	return nil
+}
+
+// Subscribe registers channel subscription.
+func (b *MemoryBus) Subscribe(channel string, queue chan bus.RawMessage) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscriptions[channel]; ok {
+		b.logger.Warn("Trying to subscribe to the same channel twice",
+			common.LogChannelToken, channel)
+		return nil
+	}
+
+	sub := &subscription{
+		buffer:   make(chan bus.RawMessage, b.Settings.BufferSize),
+		stopChan: make(chan bool),
+	}
+
+	b.subscriptions[channel] = sub
+	defaultBroker.add(channel, sub)
+	go sub.pump(queue)
+
+	b.logger.Debug("Successfully subscribed to memory channel", common.LogChannelToken, channel)
+	return nil
+}
+
+// Unsubscribe removes channel subscription.
+func (b *MemoryBus) Unsubscribe(channel string) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub, ok := b.subscriptions[channel]
+	if !ok {
+		b.logger.Warn("Trying to unsubscribe from the channel without been subscribed",
+			common.LogChannelToken, channel)
+		return
+	}
+
+	defaultBroker.remove(channel, sub)
+	close(sub.stopChan)
+	delete(b.subscriptions, channel)
+}
+
+// Publish delivers messages to every channel subscription.
+// Message is dropped for subscription which buffer is full.
+func (b *MemoryBus) Publish(channel string, messages ...interface{}) {
+	subs := defaultBroker.get(channel)
+	if 0 == len(subs) {
+		return
+	}
+
+	timeout := time.Duration(b.Settings.Timeout) * time.Millisecond
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			b.logger.Error("Failed to marshal message to channel", err, common.LogChannelToken, channel)
+			continue
+		}
+
+		for _, s := range subs {
+			msg := bus.RawMessage{
+				Body: make([]byte, len(data)),
+			}
+			copy(msg.Body, data)
+
+			if !s.put(msg, timeout) {
+				b.logger.Warn("Subscriber buffer is full, dropping message", common.LogChannelToken, channel)
+			}
+		}
+	}
+}
+
+// Ping always succeeds since no broker is involved.
+func (b *MemoryBus) Ping() error {
+	return nil
+}
+
+// Puts message into the buffer. Returns false if buffer
+// is still full after the timeout.
+func (s *subscription) put(msg bus.RawMessage, timeout time.Duration) bool {
+	select {
+	case s.buffer <- msg:
+		return true
+	case <-s.stopChan:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Passes buffered messages to the subscriber till subscription is stopped.
+func (s *subscription) pump(queue chan bus.RawMessage) {
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case msg := <-s.buffer:
+			select {
+			case queue <- msg:
+			case <-s.stopChan:
+				return
+			}
+		}
+	}
+}
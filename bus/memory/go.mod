@@ -0,0 +1,7 @@
+module go-home.io/x/providers/bus/memory
+
+require go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+go 1.13
@@ -0,0 +1,14 @@
+// Package main contains in-process loopback bus implementation for the go-home hub.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &MemoryBus{
+			subscriptions: make(map[string]*subscription),
+			Settings:      settings,
+		},
+		settings, nil
+}
@@ -0,0 +1,14 @@
+package main
+
+// Settings describes plugin settings.
+// Every subscription has own buffer, publisher waits up to
+// the timeout for a free slot before the message is dropped.
+type Settings struct {
+	BufferSize int `yaml:"bufferSize" validate:"gt=0" default:"100"`
+	Timeout    int `yaml:"timeout" validate:"gt=0" default:"1000"`
+}
+
+// Validate settings.
+func (*Settings) Validate() error {
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes expvar map shared between plugins.
+	pluginsVar = "plugins"
+	// Describes counter of messages passed to subscribers.
+	deliveredCounter = "mqtt_delivered_messages"
+	// Describes counter of messages dropped because subscriber is busy.
+	droppedCounter = "mqtt_dropped_messages"
+)
+
+// Describes single channel subscription.
+type subscription struct {
+	queue    chan bus.RawMessage
+	stopChan chan bool
+}
+
+// MqttBus describes MQTT bus plugin implementation.
+type MqttBus struct {
+	sync.Mutex
+
+	client        mqtt.Client
+	logger        common.ILoggerProvider
+	Settings      *Settings
+	subscriptions map[string]*subscription
+	codec         *envelope.Codec
+	counters      *expvar.Map
+}
+
+// Init makes an attempt to connect to MQTT broker.
+func (b *MqttBus) Init(data *bus.InitDataServiceBus) error {
+	b.logger = data.Logger
+	b.counters = getCounters()
+
+	var err error
+	b.codec, err = envelope.New(b.Settings.Envelope, data.NodeID, data.Secret)
+	if err != nil {
+		return errors.Wrap(err, "envelope init failed")
+	}
+
+	password := b.Settings.Password
+	if "" != b.Settings.PasswordSecret {
+		password, err = data.Secret.Get(b.Settings.PasswordSecret)
+		if err != nil {
+			return errors.Wrap(err, "secret get failed")
+		}
+	}
+
+	options := mqtt.NewClientOptions().
+		SetClientID(b.Settings.ClientID + "-" + data.NodeID).
+		AddBroker(b.Settings.brokerURL).
+		SetUsername(b.Settings.Login).
+		SetPassword(password).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(time.Duration(b.Settings.ReconnectInterval) * time.Second).
+		SetConnectTimeout(b.timeout()).
+		SetPingTimeout(b.timeout()).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			b.logger.Error("Lost connection to MQTT broker", err)
+		})
+
+	b.client = mqtt.NewClient(options)
+	token := b.client.Connect()
+	if !token.WaitTimeout(b.timeout()) {
+		b.client.Disconnect(0)
+		return errors.New("connection to broker timed out")
+	}
+
+	if token.Error() != nil {
+		return errors.Wrap(token.Error(), "connection to broker failed")
+	}
+
+	return nil
+}
+
+// Subscribe makes an attempts to subscribe to MQTT topic.
+func (b *MqttBus) Subscribe(channel string, queue chan bus.RawMessage) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscriptions[channel]; ok {
+		b.logger.Warn("Trying to subscribe to the same channel twice",
+			common.LogChannelToken, channel)
+		return nil
+	}
+
+	sub := &subscription{
+		queue:    queue,
+		stopChan: make(chan bool),
+	}
+
+	err := b.subscribe(channel, sub)
+	if err != nil {
+		b.logger.Error("Failed to subscribe to the channel", err, common.LogChannelToken, channel)
+		return errors.Wrap(err, "subscription failed")
+	}
+
+	b.subscriptions[channel] = sub
+	b.logger.Debug("Successfully subscribed to mqtt channel", common.LogChannelToken, channel)
+
+	return nil
+}
+
+// Unsubscribe removes channel subscription.
+func (b *MqttBus) Unsubscribe(channel string) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub, ok := b.subscriptions[channel]
+	if !ok {
+		b.logger.Warn("Trying to unsubscribe from the channel without been subscribed",
+			common.LogChannelToken, channel)
+		return
+	}
+
+	delete(b.subscriptions, channel)
+	close(sub.stopChan)
+	err := b.wait(b.client.Unsubscribe(b.getTopic(channel)))
+	if err != nil {
+		b.logger.Error("Failed to unsubscribe from the channel", err, common.LogChannelToken, channel)
+	}
+}
+
+// Publish makes an attempt to publish new messages.
+func (b *MqttBus) Publish(channel string, messages ...interface{}) {
+	topic := b.getTopic(channel)
+	for _, m := range messages {
+		data, err := b.codec.Marshal(m)
+		if err != nil {
+			b.logger.Error("Failed to marshal message to channel", err, common.LogChannelToken, channel)
+			continue
+		}
+
+		err = b.wait(b.client.Publish(topic, b.Settings.Qos, false, data))
+		if err != nil {
+			b.logger.Error("Failed to publish message to channel", err, common.LogChannelToken, channel)
+		}
+	}
+}
+
+// Ping validates whether MQTT broker is available.
+func (b *MqttBus) Ping() error {
+	if !b.client.IsConnected() {
+		err := errors.New("connection is closed")
+		b.logger.Error("Service bus is down", err)
+		return errors.Wrap(err, "ping failed")
+	}
+
+	return nil
+}
+
+// Re-subscribes all known channels after broker re-connect.
+// Session is clean, so broker doesn't keep subscriptions.
+// Subscriptions are copied, so broker calls are not made under bus lock.
+func (b *MqttBus) onConnect(mqtt.Client) {
+	b.Lock()
+	subscriptions := make(map[string]*subscription, len(b.subscriptions))
+	for channel, sub := range b.subscriptions {
+		subscriptions[channel] = sub
+	}
+	b.Unlock()
+
+	for channel, sub := range subscriptions {
+		err := b.subscribe(channel, sub)
+		if err != nil {
+			b.logger.Error("Failed to re-subscribe to the channel", err, common.LogChannelToken, channel)
+			continue
+		}
+
+		b.logger.Debug("Re-subscribed to mqtt channel", common.LogChannelToken, channel)
+	}
+}
+
+// Subscribes to MQTT topic and passes messages to the queue.
+// Messages are dropped once subscription is stopped or if subscriber
+// doesn't accept them within delivery timeout, so a single slow
+// subscriber doesn't block the whole connection.
+func (b *MqttBus) subscribe(channel string, sub *subscription) error {
+	timeout := time.Duration(b.Settings.DeliveryTimeout) * time.Millisecond
+	return b.wait(b.client.Subscribe(b.getTopic(channel), b.Settings.Qos,
+		func(_ mqtt.Client, message mqtt.Message) {
+			select {
+			case <-sub.stopChan:
+				return
+			default:
+			}
+
+			payload, _, err := b.codec.Open(message.Payload())
+			if err != nil {
+				b.logger.Warn("Rejected bus message", common.LogChannelToken, channel, "reason", err.Error())
+				return
+			}
+
+			msg := bus.RawMessage{
+				Body: make([]byte, len(payload)),
+			}
+			copy(msg.Body, payload)
+
+			select {
+			case sub.queue <- msg:
+				b.counters.Add(deliveredCounter, 1)
+			case <-sub.stopChan:
+			case <-time.After(timeout):
+				b.counters.Add(droppedCounter, 1)
+				b.logger.Warn("Subscriber is busy, message is dropped", common.LogChannelToken, channel)
+			}
+		}))
+}
+
+// Waits for MQTT operation to complete.
+func (b *MqttBus) wait(token mqtt.Token) error {
+	if !token.WaitTimeout(b.timeout()) {
+		return errors.New("operation timed out")
+	}
+
+	return token.Error()
+}
+
+// Returns MQTT topic for the bus channel.
+func (b *MqttBus) getTopic(channel string) string {
+	if "" == b.Settings.Prefix {
+		return channel
+	}
+
+	return b.Settings.Prefix + "/" + channel
+}
+
+// Returns MQTT operations timeout.
+func (b *MqttBus) timeout() time.Duration {
+	return time.Duration(b.Settings.Timeout) * time.Second
+}
+
+// Returns expvar map shared between plugins.
+func getCounters() *expvar.Map {
+	if v, ok := expvar.Get(pluginsVar).(*expvar.Map); ok {
+		return v
+	}
+
+	return expvar.NewMap(pluginsVar)
+}
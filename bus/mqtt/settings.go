@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+)
+
+// Settings describes plugin settings.
+// Every bus channel is mapped to the "<prefix>/<channel>" MQTT topic.
+// Messages which subscriber doesn't accept within delivery timeout are dropped,
+// since paho delivers all topics from a single goroutine.
+type Settings struct {
+	Broker            string `yaml:"broker" validate:"required"`
+	Login             string `yaml:"login"`
+	Password          string `yaml:"password"`
+	PasswordSecret    string `yaml:"passwordSecret"`
+	ClientID          string `yaml:"clientID" validate:"required" default:"gohome"`
+	Prefix            string `yaml:"topicsPrefix" default:"gohome/bus"`
+	Qos               byte   `yaml:"qos" validate:"gte=0,lte=2" default:"1"`
+	Timeout           int    `yaml:"timeout" validate:"gt=0" default:"2"`
+	ReconnectInterval int    `yaml:"reconnectInterval" validate:"gt=0" default:"5"`
+	DeliveryTimeout   int    `yaml:"deliveryTimeout" validate:"gt=0" default:"500"`
+
+	Envelope *envelope.Settings `yaml:"envelope"`
+
+	brokerURL string
+}
+
+// Validate settings.
+func (s *Settings) Validate() error {
+	if "" != s.Password && "" != s.PasswordSecret {
+		return errors.New("either password or password secret should be defined")
+	}
+
+	s.Prefix = strings.Trim(s.Prefix, "/")
+	if strings.ContainsAny(s.Prefix, "+#") {
+		return errors.New("topics prefix can't contain wildcards")
+	}
+
+	s.brokerURL = s.Broker
+	if !strings.Contains(s.Broker, "://") {
+		s.brokerURL = "tcp://" + s.Broker
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Returns NATS authentication options.
+func (b *NatsBus) getAuthOptions(secret common.ISecretProvider) ([]nats.Option, error) {
+	options := make([]nats.Option, 0)
+
+	if "" != b.Settings.Login {
+		password := b.Settings.Password
+		if "" != b.Settings.PasswordSecret {
+			var err error
+			password, err = secret.Get(b.Settings.PasswordSecret)
+			if err != nil {
+				return nil, errors.Wrap(err, "password secret get failed")
+			}
+		}
+
+		options = append(options, nats.UserInfo(b.Settings.Login, password))
+	}
+
+	if "" != b.Settings.TokenSecret {
+		token, err := secret.Get(b.Settings.TokenSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "token secret get failed")
+		}
+
+		options = append(options, nats.Token(token))
+	}
+
+	if "" != b.Settings.Credentials {
+		options = append(options, nats.UserCredentials(b.Settings.Credentials))
+	}
+
+	if "" != b.Settings.CredentialsSecret {
+		creds, err := secret.Get(b.Settings.CredentialsSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "credentials secret get failed")
+		}
+
+		option, err := getCredentialsOption([]byte(creds))
+		if err != nil {
+			return nil, errors.Wrap(err, "credentials parse failed")
+		}
+
+		options = append(options, option)
+	}
+
+	return options, nil
+}
+
+// Returns JWT authentication option from the .creds file content.
+func getCredentialsOption(creds []byte) (nats.Option, error) {
+	jwt, err := nkeys.ParseDecoratedJWT(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	kp, err := nkeys.ParseDecoratedUserNKey(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return nats.UserJWT(
+		func() (string, error) {
+			return jwt, nil
+		},
+		func(nonce []byte) ([]byte, error) {
+			return kp.Sign(nonce)
+		}), nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+// NatsBus describes NATS bus plugin implementation.
+type NatsBus struct {
+	sync.Mutex
+
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	logger        common.ILoggerProvider
+	nodeID        string
+	Settings      *Settings
+	subscriptions map[string]*nats.Subscription
+	codec         *envelope.Codec
+}
+
+// Init makes an attempt to connect to NATS servers.
+// NATS re-connects and re-subscribes automatically.
+func (b *NatsBus) Init(data *bus.InitDataServiceBus) error {
+	b.logger = data.Logger
+	b.nodeID = data.NodeID
+
+	var err error
+	b.codec, err = envelope.New(b.Settings.Envelope, data.NodeID, data.Secret)
+	if err != nil {
+		return errors.Wrap(err, "envelope init failed")
+	}
+
+	options, err := b.getAuthOptions(data.Secret)
+	if err != nil {
+		return errors.Wrap(err, "auth settings failed")
+	}
+
+	options = append(options,
+		nats.Name(data.NodeID),
+		nats.Timeout(b.timeout()),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(time.Duration(b.Settings.ReconnectInterval)*time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				b.logger.Error("Lost connection to nats", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			b.logger.Info("Re-connected to nats", common.LogDeviceHostToken, conn.ConnectedUrl())
+		}))
+
+	b.conn, err = nats.Connect(b.Settings.servers, options...)
+	if err != nil {
+		return errors.Wrap(err, "connection failed")
+	}
+
+	if b.Settings.JetStream != nil && b.Settings.JetStream.Enabled {
+		err = b.initJetStream()
+		if err != nil {
+			b.conn.Close()
+			return errors.Wrap(err, "jet stream init failed")
+		}
+	}
+
+	return nil
+}
+
+// Subscribe makes an attempts to subscribe to NATS subject.
+func (b *NatsBus) Subscribe(channel string, queue chan bus.RawMessage) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscriptions[channel]; ok {
+		b.logger.Warn("Trying to subscribe to the same channel twice",
+			common.LogChannelToken, channel)
+		return nil
+	}
+
+	sub, err := b.subscribe(channel, b.getHandler(channel, queue))
+	if err != nil {
+		b.logger.Error("Failed to subscribe to the channel", err, common.LogChannelToken, channel)
+		return errors.Wrap(err, "subscription failed")
+	}
+
+	b.subscriptions[channel] = sub
+	b.logger.Debug("Successfully subscribed to nats channel", common.LogChannelToken, channel)
+
+	return nil
+}
+
+// Unsubscribe removes channel subscription.
+// Jet stream subscriptions are drained, since unsubscribe deletes
+// durable consumer together with its pending messages.
+func (b *NatsBus) Unsubscribe(channel string) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub, ok := b.subscriptions[channel]
+	if !ok {
+		b.logger.Warn("Trying to unsubscribe from the channel without been subscribed",
+			common.LogChannelToken, channel)
+		return
+	}
+
+	delete(b.subscriptions, channel)
+
+	var err error
+	if b.js != nil {
+		err = sub.Drain()
+	} else {
+		err = sub.Unsubscribe()
+	}
+
+	if err != nil {
+		b.logger.Error("Failed to unsubscribe from the channel", err, common.LogChannelToken, channel)
+	}
+}
+
+// Publish makes an attempt to publish new messages.
+// With jet stream enabled every message is acknowledged by the stream.
+func (b *NatsBus) Publish(channel string, messages ...interface{}) {
+	subject := b.getSubject(channel)
+	for _, m := range messages {
+		data, err := b.codec.Marshal(m)
+		if err != nil {
+			b.logger.Error("Failed to marshal message to channel", err, common.LogChannelToken, channel)
+			continue
+		}
+
+		if b.js != nil {
+			_, err = b.js.Publish(subject, data, nats.AckWait(b.timeout()))
+		} else {
+			err = b.conn.Publish(subject, data)
+		}
+
+		if err != nil {
+			b.logger.Error("Failed to publish message to channel", err, common.LogChannelToken, channel)
+		}
+	}
+}
+
+// Ping validates whether NATS is available.
+func (b *NatsBus) Ping() error {
+	err := b.conn.FlushTimeout(b.timeout())
+	if err != nil {
+		b.logger.Error("Service bus is down", err)
+		return errors.Wrap(err, "ping failed")
+	}
+
+	return nil
+}
+
+// Subscribes to the subject according to the configured strategy.
+func (b *NatsBus) subscribe(channel string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	subject := b.getSubject(channel)
+	isQueue := subscriptionQueue == b.Settings.Subscription
+
+	switch {
+	case b.js != nil && isQueue:
+		return b.js.QueueSubscribe(subject, b.Settings.QueueGroup, handler, b.getJetStreamOptions(channel)...)
+	case b.js != nil:
+		return b.js.Subscribe(subject, handler, b.getJetStreamOptions(channel)...)
+	case isQueue:
+		return b.conn.QueueSubscribe(subject, b.Settings.QueueGroup, handler)
+	default:
+		return b.conn.Subscribe(subject, handler)
+	}
+}
+
+// Returns NATS handler which passes messages to the subscriber.
+// Jet stream messages are acknowledged once subscriber accepts them.
+// Rejected messages are acknowledged right away, since they won't become valid.
+func (b *NatsBus) getHandler(channel string, queue chan bus.RawMessage) nats.MsgHandler {
+	return func(message *nats.Msg) {
+		b.Lock()
+		_, ok := b.subscriptions[channel]
+		b.Unlock()
+		if !ok {
+			return
+		}
+
+		payload, _, err := b.codec.Open(message.Data)
+		if err != nil {
+			if envelope.ErrExpired == errors.Cause(err) {
+				b.logger.Error("Bus message is expired", err, common.LogChannelToken, channel)
+			} else {
+				b.logger.Warn("Rejected bus message", common.LogChannelToken, channel, "reason", err.Error())
+			}
+
+			b.ack(channel, message)
+			return
+		}
+
+		msg := bus.RawMessage{
+			Body: make([]byte, len(payload)),
+		}
+		copy(msg.Body, payload)
+		queue <- msg
+		b.ack(channel, message)
+	}
+}
+
+// Acknowledges jet stream message.
+func (b *NatsBus) ack(channel string, message *nats.Msg) {
+	if b.js != nil {
+		err := message.Ack()
+		if err != nil {
+			b.logger.Error("Failed to acknowledge message", err, common.LogChannelToken, channel)
+		}
+	}
+}
+
+// Returns NATS subject for the bus channel.
+func (b *NatsBus) getSubject(channel string) string {
+	if "" == b.Settings.Prefix {
+		return channel
+	}
+
+	return b.Settings.Prefix + "." + channel
+}
+
+// Returns NATS operations timeout.
+func (b *NatsBus) timeout() time.Duration {
+	return time.Duration(b.Settings.Timeout) * time.Second
+}
@@ -0,0 +1,15 @@
+module go-home.io/x/providers/bus/nats
+
+require (
+	github.com/nats-io/nats.go v1.11.0
+	github.com/nats-io/nkeys v0.3.0
+	github.com/pkg/errors v0.8.0
+	go-home.io/x/providers/bus/envelope v0.0.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+replace go-home.io/x/providers/bus/envelope => ../envelope
+
+go 1.13
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// Describes characters which are not allowed in durable consumer name.
+var invalidDurableChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Creates or updates jet stream which captures bus subjects.
+func (b *NatsBus) initJetStream() error {
+	js, err := b.conn.JetStream()
+	if err != nil {
+		return errors.Wrap(err, "jet stream context failed")
+	}
+
+	settings := b.Settings.JetStream
+	cfg := &nats.StreamConfig{
+		Name:      settings.Stream,
+		Subjects:  []string{b.Settings.Prefix + ".>"},
+		Retention: nats.LimitsPolicy,
+		MaxAge:    time.Duration(settings.MaxAge) * time.Second,
+		MaxMsgs:   settings.MaxMessages,
+		Storage:   nats.MemoryStorage,
+		Replicas:  settings.Replicas,
+	}
+
+	if settings.FileStorage {
+		cfg.Storage = nats.FileStorage
+	}
+
+	if 0 == cfg.MaxMsgs {
+		cfg.MaxMsgs = -1
+	}
+
+	_, err = js.StreamInfo(settings.Stream)
+	if nil == err {
+		_, err = js.UpdateStream(cfg)
+	} else {
+		_, err = js.AddStream(cfg)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "stream init failed")
+	}
+
+	b.js = js
+	return nil
+}
+
+// Returns durable consumer name for the channel.
+// Fan-out consumers are unique per node, queue consumers are shared.
+func (b *NatsBus) getDurableName(channel string) string {
+	owner := b.nodeID
+	if subscriptionQueue == b.Settings.Subscription {
+		owner = b.Settings.QueueGroup
+	}
+
+	return invalidDurableChars.ReplaceAllString(channel+"_"+owner, "_")
+}
+
+// Returns jet stream subscription options.
+func (b *NatsBus) getJetStreamOptions(channel string) []nats.SubOpt {
+	return []nats.SubOpt{
+		nats.Durable(b.getDurableName(channel)),
+		nats.BindStream(b.Settings.JetStream.Stream),
+		nats.DeliverNew(),
+		nats.ManualAck(),
+		nats.AckWait(time.Duration(b.Settings.JetStream.AckWait) * time.Second),
+	}
+}
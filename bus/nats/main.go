@@ -0,0 +1,18 @@
+// Package main contains NATS implementation for the go-home hub.
+package main
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &NatsBus{
+			subscriptions: make(map[string]*nats.Subscription),
+			Settings:      settings,
+		},
+		settings, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+)
+
+const (
+	// Describes subscriptions where every node receives a copy of the message.
+	subscriptionFanOut = "fanout"
+	// Describes subscriptions where nodes share messages within queue group.
+	subscriptionQueue = "queue"
+)
+
+// JetStreamSettings describes persistent stream settings.
+// Stream captures all "<prefix>.>" subjects.
+type JetStreamSettings struct {
+	Enabled     bool   `yaml:"enabled"`
+	Stream      string `yaml:"stream" validate:"required" default:"GOHOME"`
+	MaxAge      int    `yaml:"maxAge" validate:"gte=0" default:"3600"`
+	MaxMessages int64  `yaml:"maxMessages" validate:"gte=0" default:"10000"`
+	FileStorage bool   `yaml:"fileStorage" default:"true"`
+	Replicas    int    `yaml:"replicas" validate:"gt=0" default:"1"`
+	AckWait     int    `yaml:"ackWait" validate:"gt=0" default:"30"`
+}
+
+// Settings describes plugin settings.
+// Every bus channel is mapped to the "<prefix>.<channel>" NATS subject.
+// Credentials could be provided either directly or as secrets.
+type Settings struct {
+	ServerAddress     string   `yaml:"server"`
+	Servers           []string `yaml:"servers"`
+	Prefix            string   `yaml:"subjectsPrefix" default:"gohome"`
+	Timeout           int      `yaml:"timeout" validate:"gt=0" default:"2"`
+	ReconnectInterval int      `yaml:"reconnectInterval" validate:"gt=0" default:"2"`
+
+	Subscription string `yaml:"subscription" validate:"oneof=fanout queue" default:"fanout"`
+	QueueGroup   string `yaml:"queueGroup" validate:"required" default:"gohome"`
+
+	Login             string `yaml:"login"`
+	Password          string `yaml:"password"`
+	PasswordSecret    string `yaml:"passwordSecret"`
+	TokenSecret       string `yaml:"tokenSecret"`
+	Credentials       string `yaml:"credentials"`
+	CredentialsSecret string `yaml:"credentialsSecret"`
+
+	JetStream *JetStreamSettings `yaml:"jetStream"`
+	Envelope  *envelope.Settings `yaml:"envelope"`
+
+	servers string
+}
+
+// Validate settings.
+func (s *Settings) Validate() error {
+	servers := make([]string, 0)
+	for _, v := range append([]string{s.ServerAddress}, s.Servers...) {
+		if "" == v {
+			continue
+		}
+
+		if !strings.Contains(v, "://") {
+			v = "nats://" + v
+		}
+
+		servers = append(servers, v)
+	}
+
+	if 0 == len(servers) {
+		return errors.New("at least one nats server is required")
+	}
+
+	s.servers = strings.Join(servers, ",")
+
+	s.Prefix = strings.Trim(s.Prefix, ".")
+	if strings.ContainsAny(s.Prefix, "*> ") {
+		return errors.New("subjects prefix can't contain wildcards")
+	}
+
+	if "" != s.Password && "" != s.PasswordSecret {
+		return errors.New("either password or password secret should be defined")
+	}
+
+	if "" != s.Credentials && "" != s.CredentialsSecret {
+		return errors.New("either credentials or credentials secret should be defined")
+	}
+
+	if s.JetStream != nil && s.JetStream.Enabled && "" == s.Prefix {
+		return errors.New("subjects prefix is required for jet stream")
+	}
+
+	if s.JetStream != nil && s.JetStream.Enabled && s.Envelope != nil && s.Envelope.Enabled &&
+		s.Envelope.MaxAge <= s.JetStream.AckWait {
+		return errors.New("envelope max age should be greater than jet stream ack wait")
+	}
+
+	return nil
+}
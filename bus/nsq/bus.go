@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/json"
+	"expvar"
 	"sync"
 	"time"
 
 	"github.com/nsqio/go-nsq"
 	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
 	"go-home.io/x/server/plugins/bus"
 	"go-home.io/x/server/plugins/common"
 )
@@ -16,38 +17,79 @@ const (
 	nsqLogLevel = nsq.LogLevelWarning
 )
 
+// Describes error returned to nsq when subscriber doesn't accept message in time.
+var errSubscriberBusy = errors.New("subscriber is busy")
+
+// Message which should be delivered with delay.
+type deferredMessage interface {
+	PublishDelay() time.Duration
+}
+
 // NsqBus describes NSQ bus plugin implementation.
 type NsqBus struct {
 	sync.Mutex
 
 	config    *nsq.Config
-	producer  *nsq.Producer
+	producers *producerPool
 	logger    common.ILoggerProvider
 	Settings  *Settings
 	consumers map[string]*nsq.Consumer
+	retry     *retryQueue
+	counters  *expvar.Map
+	chDrain   chan bool
+	nodeID    string
+	codec     *envelope.Codec
 }
 
 // Init makes an attempt to setup a new NSQ producer.
 func (b *NsqBus) Init(data *bus.InitDataServiceBus) error {
 	b.config = nsq.NewConfig()
 	b.config.ClientID = data.NodeID
+	b.config.MaxInFlight = b.Settings.MaxInFlight
+	b.config.MaxAttempts = uint16(b.Settings.MaxAttempts)
 	b.config.DialTimeout = time.Duration(b.Settings.Timeout) * time.Second
-	b.config.LookupdPollInterval = 5 * time.Second
+	b.config.LookupdPollInterval = time.Duration(b.Settings.ReconnectInterval) * time.Second
+	b.logger = data.Logger
+	b.nodeID = data.NodeID
+
+	if b.Settings.Envelope != nil && b.Settings.Envelope.Enabled {
+		// Re-queued messages keep the original envelope,
+		// so they should be re-delivered before it expires.
+		b.config.MaxRequeueDelay = time.Duration(b.Settings.Envelope.MaxAge) * time.Second / 2
+		if b.config.DefaultRequeueDelay > b.config.MaxRequeueDelay {
+			b.config.DefaultRequeueDelay = b.config.MaxRequeueDelay
+		}
+	}
 
-	var err error
-	b.producer, err = nsq.NewProducer(b.Settings.ServerAddress, b.config)
+	err := b.applySecurity(data.Secret)
 	if err != nil {
-		return errors.Wrap(err, "producer init failed")
+		return errors.Wrap(err, "security settings failed")
 	}
 
-	b.logger = data.Logger
-	b.producer.SetLogger(&nsqLogger{logger: data.Logger}, nsqLogLevel)
+	b.codec, err = envelope.New(b.Settings.Envelope, data.NodeID, data.Secret)
+	if err != nil {
+		return errors.Wrap(err, "envelope init failed")
+	}
 
-	err = b.producer.Ping()
+	b.producers, err = newProducerPool(b.Settings.nsqds, b.config, b.logger)
 	if err != nil {
+		return errors.Wrap(err, "producers init failed")
+	}
+
+	err = b.producers.Ping()
+	if err != nil {
+		b.producers.Stop()
 		return errors.Wrap(err, "ping failed")
 	}
 
+	b.retry = &retryQueue{
+		items:   make([]*pendingPublish, 0),
+		maxSize: b.Settings.RetryQueueSize,
+	}
+	b.counters = getCounters()
+	b.chDrain = make(chan bool, 1)
+	go b.retryCycle()
+
 	return nil
 }
 
@@ -62,42 +104,79 @@ func (b *NsqBus) Subscribe(channel string, queue chan bus.RawMessage) error {
 		return nil
 	}
 
-	q, err := nsq.NewConsumer(channel, "gh", b.config)
+	q, err := nsq.NewConsumer(channel, b.getChannelName(), b.config)
 	if err != nil {
 		b.logger.Error("Failed to subscribe to the channel", err, common.LogChannelToken, channel)
 		return errors.Wrap(err, "consumer init failed")
 	}
 
-	q.AddHandler(nsq.HandlerFunc(func(message *nsq.Message) error {
-		b.Lock()
-		defer b.Unlock()
-		_, ok := b.consumers[channel]
-		if !ok {
-			return nil
-		}
-
-		msg := bus.RawMessage{
-			Body: make([]byte, len(message.Body)),
-		}
-		copy(msg.Body, message.Body)
-		queue <- msg
-		return nil
-	}))
-
+	q.AddConcurrentHandlers(b.getHandler(channel, queue), b.Settings.Concurrency)
 	q.SetLogger(&nsqLogger{logger: b.logger}, nsqLogLevel)
 
-	err = q.ConnectToNSQD(b.Settings.ServerAddress)
+	b.consumers[channel] = q
+	err = b.connectConsumer(channel, q)
 	if err != nil {
+		delete(b.consumers, channel)
+		q.Stop()
 		b.logger.Error("Failed to connect to nsq while subscribing", err, common.LogChannelToken, channel)
 		return errors.Wrap(err, "connection to bus failed")
 	}
 
 	b.logger.Debug("Successfully subscribed to nsq channel", common.LogChannelToken, channel)
-	b.consumers[channel] = q
 
 	return nil
 }
 
+// Connects consumer either to nsqlookupd or to every nsqd server.
+// nsq re-connects consumer to nsqd if established connection drops,
+// servers which were unavailable from the start are re-tried here.
+// Should be called under bus lock.
+func (b *NsqBus) connectConsumer(channel string, q *nsq.Consumer) error {
+	if len(b.Settings.Lookupds) > 0 {
+		return q.ConnectToNSQLookupds(b.Settings.Lookupds)
+	}
+
+	connected := 0
+	for _, v := range b.Settings.nsqds {
+		err := q.ConnectToNSQD(v)
+		if nil == err || nsq.ErrAlreadyConnected == err {
+			connected++
+			continue
+		}
+
+		b.logger.Warn("Failed to connect to nsqd, will retry", common.LogChannelToken, channel,
+			common.LogDeviceHostToken, v, "reason", err.Error())
+		go b.reconnectConsumer(channel, q, v)
+	}
+
+	if 0 == connected {
+		return errors.New("none of nsqd servers are available")
+	}
+
+	return nil
+}
+
+// Re-tries consumer connection to nsqd until it succeeds
+// or channel is unsubscribed.
+func (b *NsqBus) reconnectConsumer(channel string, q *nsq.Consumer, address string) {
+	for {
+		time.Sleep(b.config.LookupdPollInterval)
+
+		b.Lock()
+		current, ok := b.consumers[channel]
+		b.Unlock()
+		if !ok || current != q {
+			return
+		}
+
+		err := q.ConnectToNSQD(address)
+		if nil == err || nsq.ErrAlreadyConnected == err {
+			b.logger.Info("Connected to nsqd", common.LogChannelToken, channel, common.LogDeviceHostToken, address)
+			return
+		}
+	}
+}
+
 // Unsubscribe removes channel subscription.
 func (b *NsqBus) Unsubscribe(channel string) {
 	b.Lock()
@@ -112,29 +191,70 @@ func (b *NsqBus) Unsubscribe(channel string) {
 	}
 }
 
-// Publish makes an attempt to publish a new message.
+// Publish makes an attempt to publish new messages.
+// Messages are sent in batches, delayed messages are sent one by one.
+// Failed messages are kept in the retry queue and are sealed
+// again on every attempt, so envelope doesn't expire while queued.
 func (b *NsqBus) Publish(channel string, messages ...interface{}) {
+	batch := make([]*envelope.Prepared, 0, len(messages))
 	for _, m := range messages {
-		data, err := json.Marshal(m)
+		data, err := b.codec.Prepare(m)
 		if err != nil {
-			b.logger.Error("Failed to marshal message to channel ", err, common.LogChannelToken, channel)
+			b.logger.Error("Failed to marshal message to channel", err, common.LogChannelToken, channel)
+			continue
 		}
 
-		err = b.producer.Publish(channel, data)
-		if err != nil {
-			b.logger.Error("Failed to marshal message to channel", err,
-				"msg", string(data), common.LogChannelToken, channel)
+		delay := b.getDelay(channel, m)
+		if delay > 0 {
+			b.publish(&pendingPublish{
+				channel:  channel,
+				messages: []*envelope.Prepared{data},
+				delay:    delay,
+			})
+			continue
 		}
+
+		batch = append(batch, data)
+	}
+
+	for len(batch) > 0 {
+		size := len(batch)
+		if size > b.Settings.MaxBatchSize {
+			size = b.Settings.MaxBatchSize
+		}
+
+		b.publish(&pendingPublish{
+			channel:  channel,
+			messages: batch[:size],
+		})
+		batch = batch[size:]
 	}
 }
 
+// Returns publishing delay either from the message
+// or from the channel settings.
+func (b *NsqBus) getDelay(channel string, message interface{}) time.Duration {
+	if d, ok := message.(deferredMessage); ok {
+		return d.PublishDelay()
+	}
+
+	return time.Duration(b.Settings.Delays[channel]) * time.Millisecond
+}
+
 // Ping validates whether NSQ is available.
 func (b *NsqBus) Ping() error {
-	err := b.producer.Ping()
+	err := b.producers.Ping()
 	if err != nil {
 		b.logger.Error("Service bus is down", err)
 		return errors.Wrap(err, "ping failed")
 	}
 
+	if b.retry.Len() > 0 {
+		select {
+		case b.chDrain <- true:
+		default:
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes channel strategy where every node receives a copy of the message.
+	channelModeNode = "node"
+	// Describes channel strategy where nodes share messages as a work queue.
+	channelModeShared = "shared"
+
+	// Describes nsq ephemeral channel suffix.
+	ephemeralSuffix = "#ephemeral"
+	// Describes nsq max channel name length.
+	maxChannelLength = 64
+
+	// Describes counter of messages passed to subscribers.
+	deliveredCounter = "nsq_delivered_messages"
+	// Describes counter of messages returned to nsq because subscriber is busy.
+	requeuedCounter = "nsq_requeued_messages"
+)
+
+// Describes characters which are not allowed in nsq channel name.
+var invalidChannelChars = regexp.MustCompile(`[^.a-zA-Z0-9_-]`)
+
+// Returns nsq channel name according to the configured strategy.
+func (b *NsqBus) getChannelName() string {
+	name := b.Settings.ChannelName
+	if channelModeNode == b.Settings.ChannelMode {
+		name = name + "-" + invalidChannelChars.ReplaceAllString(b.nodeID, "_")
+	}
+
+	max := maxChannelLength
+	if b.Settings.EphemeralChannels {
+		max -= len(ephemeralSuffix)
+	}
+
+	if len(name) > max {
+		name = name[:max]
+	}
+
+	if b.Settings.EphemeralChannels {
+		name += ephemeralSuffix
+	}
+
+	return name
+}
+
+// Returns nsq handler which passes messages to the subscriber.
+// If subscriber doesn't accept message within delivery timeout,
+// message is returned to nsq which backs off the consumer.
+func (b *NsqBus) getHandler(channel string, queue chan bus.RawMessage) nsq.HandlerFunc {
+	timeout := time.Duration(b.Settings.DeliveryTimeout) * time.Millisecond
+	return func(message *nsq.Message) error {
+		b.Lock()
+		_, ok := b.consumers[channel]
+		b.Unlock()
+		if !ok {
+			return nil
+		}
+
+		payload, e, err := b.codec.Open(message.Body)
+		if err != nil {
+			if envelope.ErrExpired == errors.Cause(err) {
+				b.logger.Error("Bus message is expired", err, common.LogChannelToken, channel)
+			} else {
+				b.logger.Warn("Rejected bus message", common.LogChannelToken, channel, "reason", err.Error())
+			}
+
+			return nil
+		}
+
+		msg := bus.RawMessage{
+			Body: make([]byte, len(payload)),
+		}
+		copy(msg.Body, payload)
+
+		select {
+		case queue <- msg:
+			b.counters.Add(deliveredCounter, 1)
+			return nil
+		case <-time.After(timeout):
+			if e != nil {
+				b.codec.Forget(e.ID)
+			}
+
+			b.counters.Add(requeuedCounter, 1)
+			b.logger.Debug("Subscriber is busy, message is re-queued", common.LogChannelToken, channel)
+			return errSubscriberBusy
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/nsqio/go-nsq"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Set of nsqd producers. Messages are published through the active
+// producer, next one is used if publishing fails.
+type producerPool struct {
+	sync.Mutex
+
+	logger    common.ILoggerProvider
+	producers []*nsq.Producer
+	active    int
+}
+
+// Constructs a new producers pool.
+func newProducerPool(addresses []string, config *nsq.Config, logger common.ILoggerProvider) (*producerPool, error) {
+	p := &producerPool{
+		logger:    logger,
+		producers: make([]*nsq.Producer, 0, len(addresses)),
+	}
+
+	for _, v := range addresses {
+		producer, err := nsq.NewProducer(v, config)
+		if err != nil {
+			p.Stop()
+			return nil, errors.Wrapf(err, "producer init failed for %s", v)
+		}
+
+		producer.SetLogger(&nsqLogger{logger: logger}, nsqLogLevel)
+		p.producers = append(p.producers, producer)
+	}
+
+	return p, nil
+}
+
+// Ping validates whether at least one producer is available.
+// The first available producer becomes active.
+func (p *producerPool) Ping() error {
+	return p.Do(func(producer *nsq.Producer) error {
+		return producer.Ping()
+	})
+}
+
+// Do invokes action on the active producer and fails over
+// to the next one in case of error.
+func (p *producerPool) Do(action func(producer *nsq.Producer) error) error {
+	p.Lock()
+	start := p.active
+	p.Unlock()
+
+	var err error
+	for i := 0; i < len(p.producers); i++ {
+		index := (start + i) % len(p.producers)
+		err = action(p.producers[index])
+		if nil == err {
+			p.setActive(index)
+			return nil
+		}
+
+		p.logger.Warn("nsqd producer failed", common.LogDeviceHostToken,
+			p.producers[index].String(), "reason", err.Error())
+	}
+
+	return errors.Wrap(err, "all nsqd producers failed")
+}
+
+// Stop stops all producers.
+func (p *producerPool) Stop() {
+	for _, v := range p.producers {
+		v.Stop()
+	}
+}
+
+// Switches active producer.
+func (p *producerPool) setActive(index int) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.active != index {
+		p.logger.Info("Switched active nsqd producer", common.LogDeviceHostToken, p.producers[index].String())
+		p.active = index
+	}
+}
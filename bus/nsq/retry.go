@@ -0,0 +1,223 @@
+package main
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes expvar map shared between plugins.
+	pluginsVar = "plugins"
+	// Describes counter of messages dropped because retry queue is full.
+	droppedCounter = "nsq_dropped_messages"
+	// Describes counter of messages published after retry.
+	retriedCounter = "nsq_retried_messages"
+)
+
+// Publish request which wasn't delivered yet.
+// Requests with delay contain a single message.
+type pendingPublish struct {
+	channel  string
+	messages []*envelope.Prepared
+	delay    time.Duration
+}
+
+// Bounded FIFO queue of failed publish requests.
+// The oldest requests are dropped once queue is full.
+type retryQueue struct {
+	sync.Mutex
+
+	items   []*pendingPublish
+	maxSize int
+	dropped int64
+}
+
+// Push adds requests to the end of the queue.
+// Returns number of dropped requests.
+func (q *retryQueue) Push(items ...*pendingPublish) int {
+	q.Lock()
+	defer q.Unlock()
+
+	q.items = append(q.items, items...)
+	return q.trim()
+}
+
+// Peek returns the oldest request without removing it.
+// Returns nil if queue is empty.
+func (q *retryQueue) Peek() *pendingPublish {
+	q.Lock()
+	defer q.Unlock()
+
+	if 0 == len(q.items) {
+		return nil
+	}
+
+	return q.items[0]
+}
+
+// Remove removes the oldest request if it wasn't dropped yet.
+func (q *retryQueue) Remove(item *pendingPublish) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.items) > 0 && q.items[0] == item {
+		q.items = q.items[1:]
+	}
+}
+
+// Len returns number of queued requests.
+func (q *retryQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return len(q.items)
+}
+
+// Dropped returns total number of dropped messages.
+func (q *retryQueue) Dropped() int64 {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.dropped
+}
+
+// Removes the oldest requests above the limit.
+// Should be called under queue lock.
+func (q *retryQueue) trim() int {
+	extra := len(q.items) - q.maxSize
+	if extra <= 0 {
+		return 0
+	}
+
+	for _, v := range q.items[:extra] {
+		q.dropped += int64(len(v.messages))
+	}
+
+	q.items = q.items[extra:]
+	return extra
+}
+
+// Sends publish request through the available producer.
+// Messages are sealed right before sending.
+func (b *NsqBus) send(p *pendingPublish) error {
+	body := make([][]byte, 0, len(p.messages))
+	for _, v := range p.messages {
+		data, err := b.codec.Seal(v)
+		if err != nil {
+			return errors.Wrap(err, "seal failed")
+		}
+
+		body = append(body, data)
+	}
+
+	return b.producers.Do(func(producer *nsq.Producer) error {
+		switch {
+		case p.delay > 0:
+			return producer.DeferredPublish(p.channel, p.delay, body[0])
+		case 1 == len(body):
+			return producer.Publish(p.channel, body[0])
+		default:
+			return producer.MultiPublish(p.channel, body)
+		}
+	})
+}
+
+// Sends publish request or puts it into the retry queue.
+// While queue isn't empty, new requests are queued to keep messages order.
+func (b *NsqBus) publish(p *pendingPublish) {
+	if b.retry.Len() > 0 {
+		b.enqueue(p)
+		return
+	}
+
+	err := b.send(p)
+	if err != nil {
+		b.logger.Error("Failed to publish message, will retry", err, common.LogChannelToken, p.channel)
+		b.enqueue(p)
+	}
+}
+
+// Puts publish request into the retry queue.
+func (b *NsqBus) enqueue(p *pendingPublish) {
+	dropped := b.retry.Push(p)
+	if dropped > 0 {
+		b.logger.Warn("Retry queue is full, dropping the oldest messages",
+			common.LogChannelToken, p.channel)
+		b.counters.Set(droppedCounter, expvarInt(b.retry.Dropped()))
+	}
+}
+
+// Re-sends queued requests with exponential backoff.
+// Queue is drained right away once Ping succeeds.
+func (b *NsqBus) retryCycle() {
+	minBackoff := time.Duration(b.Settings.RetryInterval) * time.Second
+	maxBackoff := time.Duration(b.Settings.MaxRetryInterval) * time.Second
+	backoff := minBackoff
+
+	for {
+		select {
+		case <-b.chDrain:
+		case <-time.After(backoff):
+		}
+
+		if 0 == b.retry.Len() {
+			backoff = minBackoff
+			continue
+		}
+
+		if b.drain() {
+			backoff = minBackoff
+			continue
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Sends queued requests. Returns false if bus is still unavailable.
+// Requests stay in the queue till they are sent, so new messages
+// are queued behind them.
+func (b *NsqBus) drain() bool {
+	for {
+		item := b.retry.Peek()
+		if nil == item {
+			break
+		}
+
+		err := b.send(item)
+		if err != nil {
+			return false
+		}
+
+		b.retry.Remove(item)
+		b.counters.Add(retriedCounter, int64(len(item.messages)))
+	}
+
+	b.logger.Info("Retry queue is drained")
+	return true
+}
+
+// Returns expvar map shared between plugins.
+func getCounters() *expvar.Map {
+	if v, ok := expvar.Get(pluginsVar).(*expvar.Map); ok {
+		return v
+	}
+
+	return expvar.NewMap(pluginsVar)
+}
+
+// Wraps int value into expvar.
+func expvarInt(value int64) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(value)
+	return v
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"go-home.io/x/providers/bus/envelope"
+)
+
+// Returns a new publish request with the number of messages.
+func getPending(channel string, messages int) *pendingPublish {
+	p := &pendingPublish{channel: channel}
+	for i := 0; i < messages; i++ {
+		p.messages = append(p.messages, &envelope.Prepared{})
+	}
+
+	return p
+}
+
+// Tests that the oldest requests are dropped once queue is full.
+func TestRetryQueueTrim(t *testing.T) {
+	data := []struct {
+		name     string
+		maxSize  int
+		pushes   [][]int
+		dropped  int
+		messages int64
+		first    string
+	}{
+		{
+			name:    "below limit",
+			maxSize: 3,
+			pushes:  [][]int{{1}, {1}},
+			first:   "0",
+		},
+		{
+			name:    "at limit",
+			maxSize: 2,
+			pushes:  [][]int{{1}, {1}},
+			first:   "0",
+		},
+		{
+			name:     "single overflow",
+			maxSize:  2,
+			pushes:   [][]int{{1}, {1}, {1}},
+			dropped:  1,
+			messages: 1,
+			first:    "1",
+		},
+		{
+			name:     "multi publish dropped",
+			maxSize:  1,
+			pushes:   [][]int{{3}, {1}},
+			dropped:  1,
+			messages: 3,
+			first:    "1",
+		},
+		{
+			name:     "batch overflow",
+			maxSize:  2,
+			pushes:   [][]int{{2, 1, 1, 1}},
+			dropped:  2,
+			messages: 3,
+			first:    "2",
+		},
+	}
+
+	for _, v := range data {
+		t.Run(v.name, func(t *testing.T) {
+			q := &retryQueue{maxSize: v.maxSize}
+			dropped := 0
+			n := 0
+			for _, push := range v.pushes {
+				items := make([]*pendingPublish, 0, len(push))
+				for _, messages := range push {
+					items = append(items, getPending(strconv.Itoa(n), messages))
+					n++
+				}
+
+				dropped += q.Push(items...)
+			}
+
+			if v.dropped != dropped {
+				t.Fatalf("expected %d dropped requests, got %d", v.dropped, dropped)
+			}
+
+			if v.messages != q.Dropped() {
+				t.Fatalf("expected %d dropped messages, got %d", v.messages, q.Dropped())
+			}
+
+			if n-v.dropped != q.Len() {
+				t.Fatalf("expected %d queued requests, got %d", n-v.dropped, q.Len())
+			}
+
+			if v.first != q.Peek().channel {
+				t.Fatalf("expected %s to be the oldest request, got %s", v.first, q.Peek().channel)
+			}
+		})
+	}
+}
+
+// Tests that requests queued during drain are sent after the older ones.
+func TestRetryQueueDrainOrder(t *testing.T) {
+	q := &retryQueue{maxSize: 10}
+	q.Push(getPending("0", 1), getPending("1", 1))
+
+	sent := make([]string, 0)
+	for {
+		item := q.Peek()
+		if nil == item {
+			break
+		}
+
+		if 1 == len(sent) {
+			q.Push(getPending("2", 1))
+		}
+
+		sent = append(sent, item.channel)
+		q.Remove(item)
+	}
+
+	for i, v := range sent {
+		if strconv.Itoa(i) != v {
+			t.Fatalf("expected request %d to be sent, got %s", i, v)
+		}
+	}
+
+	if 3 != len(sent) {
+		t.Fatalf("expected 3 sent requests, got %d", len(sent))
+	}
+}
+
+// Tests that removal of already dropped request keeps the queue intact.
+func TestRetryQueueRemoveDropped(t *testing.T) {
+	q := &retryQueue{maxSize: 1}
+	q.Push(getPending("0", 1))
+
+	item := q.Peek()
+	q.Push(getPending("1", 1))
+	q.Remove(item)
+
+	if 1 != q.Len() || "1" != q.Peek().channel {
+		t.Fatal("newer request was removed instead of the dropped one")
+	}
+}
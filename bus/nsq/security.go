@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Applies TLS, authentication and compression settings to nsq config.
+func (b *NsqBus) applySecurity(secret common.ISecretProvider) error {
+	authSecret := b.Settings.AuthSecret
+	if "" != b.Settings.AuthSecretName {
+		var err error
+		authSecret, err = getSecret(secret, b.Settings.AuthSecretName)
+		if err != nil {
+			return errors.Wrap(err, "auth secret load failed")
+		}
+	}
+
+	b.config.AuthSecret = authSecret
+
+	switch b.Settings.Compression {
+	case compressionDeflate:
+		b.config.Deflate = true
+		b.config.DeflateLevel = b.Settings.DeflateLevel
+	case compressionSnappy:
+		b.config.Snappy = true
+	}
+
+	if nil == b.Settings.TLS {
+		return nil
+	}
+
+	tlsConfig, err := getTLSConfig(b.Settings.TLS, secret)
+	if err != nil {
+		return errors.Wrap(err, "tls config failed")
+	}
+
+	b.config.TlsV1 = true
+	b.config.TlsConfig = tlsConfig
+	return nil
+}
+
+// Builds TLS config.
+func getTLSConfig(settings *TLSSettings, secret common.ISecretProvider) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         settings.ServerName,
+		InsecureSkipVerify: settings.SkipVerify, // nolint: gosec
+	}
+
+	ca, err := readPEM(settings.CA, settings.CASecret, secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "ca load failed")
+	}
+
+	if nil != ca {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("ca doesn't contain valid certificates")
+		}
+
+		config.RootCAs = pool
+	}
+
+	cert, err := readPEM(settings.Cert, settings.CertSecret, secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "client certificate load failed")
+	}
+
+	key, err := readPEM(settings.Key, settings.KeySecret, secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "client key load failed")
+	}
+
+	if nil != cert {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "client certificate parse failed")
+		}
+
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
+
+// Reads PEM content either from the secret or from the file.
+// Returns nil if neither is provided.
+func readPEM(file string, secretName string, secret common.ISecretProvider) ([]byte, error) {
+	if "" != secretName {
+		data, err := getSecret(secret, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(data), nil
+	}
+
+	if "" == file {
+		return nil, nil
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+// Returns secret value.
+func getSecret(secret common.ISecretProvider, name string) (string, error) {
+	if nil == secret {
+		return "", errors.New("secret provider is not available")
+	}
+
+	data, err := secret.Get(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "secret %s not found", name)
+	}
+
+	return data, nil
+}
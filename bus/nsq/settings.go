@@ -1,12 +1,99 @@
 package main
 
+import (
+	"github.com/nsqio/go-nsq"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+)
+
+const (
+	// Describes disabled compression.
+	compressionNone = "none"
+	// Describes deflate compression.
+	compressionDeflate = "deflate"
+	// Describes snappy compression.
+	compressionSnappy = "snappy"
+)
+
+// TLSSettings describes TLS connection settings.
+// Certificates could be provided either as files or as secrets with PEM content.
+type TLSSettings struct {
+	CA         string `yaml:"ca"`
+	CASecret   string `yaml:"caSecret"`
+	Cert       string `yaml:"cert"`
+	CertSecret string `yaml:"certSecret"`
+	Key        string `yaml:"key"`
+	KeySecret  string `yaml:"keySecret"`
+	ServerName string `yaml:"serverName"`
+	SkipVerify bool   `yaml:"skipVerify"`
+}
+
 // Settings describes plugin settings.
+// Producers are using nsqd servers, consumers are using either
+// nsqlookupd servers, if defined, or nsqd servers directly.
+// In node channel mode every node receives a copy of the message,
+// in shared mode nodes are load balanced. Shared mode is the default
+// for backward compatibility with the fixed "gh" channel, node mode
+// should be set explicitly for fan-out between nodes.
+// MaxAttempts limits re-deliveries of messages rejected by busy subscribers.
 type Settings struct {
-	ServerAddress string `yaml:"server" validate:"required"`
-	Timeout       int    `yaml:"timeout" validate:"gt=0" default:"1"`
+	ServerAddress     string   `yaml:"server"`
+	Servers           []string `yaml:"servers"`
+	Lookupds          []string `yaml:"lookupds"`
+	Timeout           int      `yaml:"timeout" validate:"gt=0" default:"1"`
+	ReconnectInterval int      `yaml:"reconnectInterval" validate:"gt=0" default:"5"`
+
+	TLS            *TLSSettings `yaml:"tls"`
+	AuthSecret     string       `yaml:"authSecret"`
+	AuthSecretName string       `yaml:"authSecretName"`
+	Compression    string       `yaml:"compression" validate:"oneof=none deflate snappy" default:"none"`
+	DeflateLevel   int          `yaml:"deflateLevel" validate:"gte=1,lte=9" default:"6"`
+
+	MaxBatchSize     int            `yaml:"maxBatchSize" validate:"gt=0" default:"100"`
+	Delays           map[string]int `yaml:"delays"`
+	RetryQueueSize   int            `yaml:"retryQueueSize" validate:"gt=0" default:"1000"`
+	RetryInterval    int            `yaml:"retryInterval" validate:"gt=0" default:"1"`
+	MaxRetryInterval int            `yaml:"maxRetryInterval" validate:"gt=0" default:"60"`
+
+	ChannelMode       string `yaml:"channelMode" validate:"oneof=node shared" default:"shared"`
+	ChannelName       string `yaml:"channelName" validate:"required" default:"gh"`
+	EphemeralChannels bool   `yaml:"ephemeralChannels"`
+	MaxInFlight       int    `yaml:"maxInFlight" validate:"gt=0" default:"10"`
+	Concurrency       int    `yaml:"concurrency" validate:"gt=0" default:"1"`
+	MaxAttempts       int    `yaml:"maxAttempts" validate:"gt=0,lte=65535" default:"5"`
+	DeliveryTimeout   int    `yaml:"deliveryTimeout" validate:"gt=0" default:"500"`
+
+	Envelope *envelope.Settings `yaml:"envelope"`
+
+	nsqds []string
 }
 
 // Validate settings.
 func (s *Settings) Validate() error {
+	s.nsqds = make([]string, 0)
+	known := make(map[string]bool)
+	for _, v := range append([]string{s.ServerAddress}, s.Servers...) {
+		if "" == v || known[v] {
+			continue
+		}
+
+		known[v] = true
+		s.nsqds = append(s.nsqds, v)
+	}
+
+	if 0 == len(s.nsqds) {
+		return errors.New("at least one nsqd server is required")
+	}
+
+	if !nsq.IsValidChannelName(s.ChannelName) {
+		return errors.New("invalid channel name")
+	}
+
+	if s.TLS != nil {
+		if ("" == s.TLS.Cert && "" == s.TLS.CertSecret) != ("" == s.TLS.Key && "" == s.TLS.KeySecret) {
+			return errors.New("both tls client certificate and key are required")
+		}
+	}
+
 	return nil
 }
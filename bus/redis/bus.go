@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes stream message field with the payload.
+	bodyField = "body"
+	// Describes how long XREADGROUP waits for new messages.
+	blockInterval = 1 * time.Second
+)
+
+// Describes single channel subscription.
+type subscription struct {
+	queue    chan bus.RawMessage
+	stopChan chan bool
+}
+
+// RedisBus describes Redis Streams bus plugin implementation.
+type RedisBus struct {
+	sync.Mutex
+
+	client        *redis.Client
+	logger        common.ILoggerProvider
+	nodeID        string
+	Settings      *Settings
+	subscriptions map[string]*subscription
+	codec         *envelope.Codec
+}
+
+// Init makes an attempt to connect to Redis.
+func (b *RedisBus) Init(data *bus.InitDataServiceBus) error {
+	b.logger = data.Logger
+	b.nodeID = data.NodeID
+
+	var err error
+	b.codec, err = envelope.New(b.Settings.Envelope, data.NodeID, data.Secret)
+	if err != nil {
+		return errors.Wrap(err, "envelope init failed")
+	}
+
+	password := b.Settings.Password
+	if "" != b.Settings.PasswordSecret {
+		password, err = data.Secret.Get(b.Settings.PasswordSecret)
+		if err != nil {
+			return errors.Wrap(err, "secret get failed")
+		}
+	}
+
+	b.client = redis.NewClient(&redis.Options{
+		Addr:         b.Settings.Address,
+		Password:     password,
+		DB:           b.Settings.Database,
+		DialTimeout:  b.timeout(),
+		ReadTimeout:  b.timeout() + blockInterval,
+		WriteTimeout: b.timeout(),
+	})
+
+	err = b.client.Ping().Err()
+	if err != nil {
+		b.client.Close() // nolint: gosec, errcheck
+		return errors.Wrap(err, "ping failed")
+	}
+
+	return nil
+}
+
+// Subscribe makes an attempts to subscribe to Redis stream.
+// Consumer group is created if it doesn't exist yet.
+func (b *RedisBus) Subscribe(channel string, queue chan bus.RawMessage) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscriptions[channel]; ok {
+		b.logger.Warn("Trying to subscribe to the same channel twice",
+			common.LogChannelToken, channel)
+		return nil
+	}
+
+	err := b.client.Do("xgroup", "create", b.getStream(channel), b.getGroup(), "$", "mkstream").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		b.logger.Error("Failed to subscribe to the channel", err, common.LogChannelToken, channel)
+		return errors.Wrap(err, "group create failed")
+	}
+
+	sub := &subscription{
+		queue:    queue,
+		stopChan: make(chan bool),
+	}
+
+	b.subscriptions[channel] = sub
+	go b.readCycle(channel, sub)
+
+	b.logger.Debug("Successfully subscribed to redis channel", common.LogChannelToken, channel)
+	return nil
+}
+
+// Unsubscribe removes channel subscription.
+// Consumer group is kept, so messages are not lost till the next subscription.
+func (b *RedisBus) Unsubscribe(channel string) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub, ok := b.subscriptions[channel]
+	if !ok {
+		b.logger.Warn("Trying to unsubscribe from the channel without been subscribed",
+			common.LogChannelToken, channel)
+		return
+	}
+
+	close(sub.stopChan)
+	delete(b.subscriptions, channel)
+}
+
+// Publish makes an attempt to publish new messages.
+// Stream is trimmed with every added message.
+func (b *RedisBus) Publish(channel string, messages ...interface{}) {
+	stream := b.getStream(channel)
+	_, err := b.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, m := range messages {
+			data, err := b.codec.Marshal(m)
+			if err != nil {
+				b.logger.Error("Failed to marshal message to channel", err, common.LogChannelToken, channel)
+				continue
+			}
+
+			args := &redis.XAddArgs{
+				Stream: stream,
+				Values: map[string]interface{}{bodyField: data},
+			}
+
+			if b.Settings.ExactTrim {
+				args.MaxLen = b.Settings.MaxLen
+			} else {
+				args.MaxLenApprox = b.Settings.MaxLen
+			}
+
+			pipe.XAdd(args)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		b.logger.Error("Failed to publish messages to channel", err, common.LogChannelToken, channel)
+	}
+}
+
+// Ping validates whether Redis is available.
+func (b *RedisBus) Ping() error {
+	err := b.client.Ping().Err()
+	if err != nil {
+		b.logger.Error("Service bus is down", err)
+		return errors.Wrap(err, "ping failed")
+	}
+
+	return nil
+}
+
+// Returns Redis stream key for the bus channel.
+func (b *RedisBus) getStream(channel string) string {
+	if "" == b.Settings.Prefix {
+		return channel
+	}
+
+	return b.Settings.Prefix + ":" + channel
+}
+
+// Returns consumer group according to the configured strategy.
+func (b *RedisBus) getGroup() string {
+	if subscriptionQueue == b.Settings.Subscription {
+		return b.Settings.Group
+	}
+
+	return b.Settings.Group + ":" + b.nodeID
+}
+
+// Returns Redis operations timeout.
+func (b *RedisBus) timeout() time.Duration {
+	return time.Duration(b.Settings.Timeout) * time.Second
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+	"go-home.io/x/server/plugins/bus"
+	"go-home.io/x/server/plugins/common"
+)
+
+// Reads channel stream till subscription is stopped.
+// Messages which were delivered but not acknowledged before restart
+// are read first, messages abandoned by other consumers are re-claimed periodically.
+func (b *RedisBus) readCycle(channel string, sub *subscription) {
+	stream := b.getStream(channel)
+	group := b.getGroup()
+	lastID := "0"
+	claimTicker := time.NewTicker(time.Duration(b.Settings.ClaimInterval) * time.Second)
+	defer claimTicker.Stop()
+
+	for {
+		select {
+		case <-sub.stopChan:
+			return
+		case <-claimTicker.C:
+			b.reclaim(channel, sub, stream, group)
+			continue
+		default:
+		}
+
+		streams, err := b.client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: b.nodeID,
+			Streams:  []string{stream, lastID},
+			Count:    b.Settings.BatchSize,
+			Block:    blockInterval,
+		}).Result()
+
+		if err != nil && err != redis.Nil {
+			b.logger.Error("Failed to read from the channel", err, common.LogChannelToken, channel)
+			if !b.sleep(sub) {
+				return
+			}
+
+			continue
+		}
+
+		received := 0
+		for _, s := range streams {
+			received += len(s.Messages)
+			if !b.deliver(channel, sub, stream, group, s.Messages) {
+				return
+			}
+		}
+
+		if 0 == received && "0" == lastID {
+			lastID = ">"
+		}
+	}
+}
+
+// Claims pending messages which were not acknowledged by other consumers
+// within the idle time.
+func (b *RedisBus) reclaim(channel string, sub *subscription, stream string, group string) {
+	minIdle := time.Duration(b.Settings.ClaimIdle) * time.Second
+	pending, err := b.client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  "-",
+		End:    "+",
+		Count:  b.Settings.BatchSize,
+	}).Result()
+
+	if err != nil {
+		b.logger.Error("Failed to get pending messages", err, common.LogChannelToken, channel)
+		return
+	}
+
+	ids := make([]string, 0)
+	for _, v := range pending {
+		if v.Consumer != b.nodeID && v.Idle >= minIdle {
+			ids = append(ids, v.Id)
+		}
+	}
+
+	if 0 == len(ids) {
+		return
+	}
+
+	messages, err := b.client.XClaim(&redis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: b.nodeID,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+
+	if err != nil {
+		b.logger.Error("Failed to claim pending messages", err, common.LogChannelToken, channel)
+		return
+	}
+
+	b.logger.Info("Claimed abandoned messages", common.LogChannelToken, channel, "count", strconv.Itoa(len(messages)))
+	b.deliver(channel, sub, stream, group, messages)
+}
+
+// Passes messages to the subscriber and acknowledges them.
+// Rejected messages are acknowledged as well, since they won't become valid.
+// Returns false if subscription was stopped.
+func (b *RedisBus) deliver(channel string, sub *subscription, stream string, group string,
+	messages []redis.XMessage) bool {
+	for _, m := range messages {
+		body, ok := m.Values[bodyField].(string)
+		if !ok {
+			b.logger.Warn("Received message without body", common.LogChannelToken, channel)
+		} else if payload, _, err := b.codec.Open([]byte(body)); err != nil {
+			if envelope.ErrExpired == errors.Cause(err) {
+				b.logger.Error("Bus message is expired", err, common.LogChannelToken, channel)
+			} else {
+				b.logger.Warn("Rejected bus message", common.LogChannelToken, channel, "reason", err.Error())
+			}
+		} else {
+			select {
+			case sub.queue <- bus.RawMessage{Body: payload}:
+			case <-sub.stopChan:
+				return false
+			}
+		}
+
+		err := b.client.XAck(stream, group, m.ID).Err()
+		if err != nil {
+			b.logger.Error("Failed to acknowledge message", err, common.LogChannelToken, channel)
+		}
+	}
+
+	return true
+}
+
+// Waits before the next read attempt.
+// Returns false if subscription was stopped.
+func (b *RedisBus) sleep(sub *subscription) bool {
+	select {
+	case <-sub.stopChan:
+		return false
+	case <-time.After(b.timeout()):
+		return true
+	}
+}
@@ -0,0 +1,14 @@
+module go-home.io/x/providers/bus/redis
+
+require (
+	github.com/go-redis/redis v6.14.1+incompatible
+	github.com/pkg/errors v0.8.0
+	go-home.io/x/providers/bus/envelope v0.0.0
+	go-home.io/x/server/plugins v0.0.0-20181025030525-18e916b213bc
+)
+
+replace go-home.io/x/server/plugins => ../../../server/plugins
+
+replace go-home.io/x/providers/bus/envelope => ../envelope
+
+go 1.13
@@ -0,0 +1,14 @@
+// Package main contains Redis Streams implementation for the go-home hub.
+package main
+
+// Load is the main plugin entry point.
+// nolint: deadcode
+func Load() (interface{}, interface{}, error) {
+	settings := &Settings{}
+
+	return &RedisBus{
+			subscriptions: make(map[string]*subscription),
+			Settings:      settings,
+		},
+		settings, nil
+}
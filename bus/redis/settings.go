@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go-home.io/x/providers/bus/envelope"
+)
+
+const (
+	// Describes subscriptions where every node has own consumer group.
+	subscriptionFanOut = "fanout"
+	// Describes subscriptions where nodes share the same consumer group.
+	subscriptionQueue = "queue"
+)
+
+// Settings describes plugin settings.
+// Every bus channel is mapped to the "<prefix>:<channel>" stream.
+// Streams are trimmed to approximately MaxLen messages.
+type Settings struct {
+	Address        string `yaml:"address" validate:"required" default:"localhost:6379"`
+	Password       string `yaml:"password"`
+	PasswordSecret string `yaml:"passwordSecret"`
+	Database       int    `yaml:"db" validate:"gte=0"`
+	Prefix         string `yaml:"keysPrefix" default:"gohome:bus"`
+	Timeout        int    `yaml:"timeout" validate:"gt=0" default:"2"`
+
+	Subscription string `yaml:"subscription" validate:"oneof=fanout queue" default:"fanout"`
+	Group        string `yaml:"group" validate:"required" default:"gohome"`
+	MaxLen       int64  `yaml:"maxLen" validate:"gte=0" default:"10000"`
+	ExactTrim    bool   `yaml:"exactTrim"`
+	BatchSize    int64  `yaml:"batchSize" validate:"gt=0" default:"10"`
+
+	ClaimInterval int `yaml:"claimInterval" validate:"gt=0" default:"10"`
+	ClaimIdle     int `yaml:"claimIdle" validate:"gt=0" default:"30"`
+
+	Envelope *envelope.Settings `yaml:"envelope"`
+}
+
+// Validate settings.
+func (s *Settings) Validate() error {
+	if "" != s.Password && "" != s.PasswordSecret {
+		return errors.New("either password or password secret should be defined")
+	}
+
+	if s.Envelope != nil && s.Envelope.Enabled && s.Envelope.MaxAge <= s.ClaimIdle+s.ClaimInterval {
+		return errors.New("envelope max age should be greater than claim idle and interval")
+	}
+
+	s.Prefix = strings.TrimRight(s.Prefix, ":")
+	return nil
+}
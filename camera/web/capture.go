@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"time"
+
+	chrome "github.com/mkenney/go-chrome/tot"
+	"github.com/mkenney/go-chrome/tot/emulation"
+	"github.com/mkenney/go-chrome/tot/socket"
+	"github.com/pkg/errors"
+)
+
+// Describes Page.captureScreenshot parameters.
+// Sent as raw command since webp format and capturing beyond
+// viewport are not covered by the typed chrome API.
+type captureParams struct {
+	Format                string       `json:"format"`
+	Quality               int          `json:"quality,omitempty"`
+	Clip                  *captureClip `json:"clip,omitempty"`
+	CaptureBeyondViewport bool         `json:"captureBeyondViewport,omitempty"`
+}
+
+// Describes screenshot region.
+type captureClip struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Scale  float64 `json:"scale"`
+}
+
+// Describes Page.captureScreenshot result.
+type captureResult struct {
+	Data string `json:"data"`
+}
+
+// Describes Emulation.setEmulatedMedia parameters.
+type mediaParams struct {
+	Media    string         `json:"media"`
+	Features []mediaFeature `json:"features,omitempty"`
+}
+
+// Describes emulated CSS media feature.
+type mediaFeature struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Applies viewport size, scale, orientation and color scheme to the tab.
+func (c *WebCamera) setupTab(t *chrome.Tab) error {
+	orientation := &emulation.ScreenOrientation{
+		Type:  emulation.OrientationType.LandscapePrimary,
+		Angle: 90,
+	}
+
+	if orientationPortrait == c.Settings.Orientation {
+		orientation = &emulation.ScreenOrientation{
+			Type:  emulation.OrientationType.PortraitPrimary,
+			Angle: 0,
+		}
+	}
+
+	overrideResult := <-t.Emulation().SetDeviceMetricsOverride(
+		&emulation.SetDeviceMetricsOverrideParams{
+			Width:             c.Settings.Width,
+			Height:            c.Settings.Height,
+			DeviceScaleFactor: c.Settings.Scale,
+			ScreenOrientation: orientation,
+		},
+	)
+	if nil != overrideResult.Err {
+		return errors.Wrap(overrideResult.Err, "metrics override failed")
+	}
+
+	// Page default color scheme is kept unless dark mode is requested.
+	media := &mediaParams{
+		Media: "screen",
+	}
+
+	if c.Settings.DarkMode {
+		media.Features = []mediaFeature{{Name: "prefers-color-scheme", Value: "dark"}}
+	}
+
+	err := c.sendCommand(t, "Emulation.setEmulatedMedia", media, nil)
+	if err != nil {
+		return errors.Wrap(err, "media emulation failed")
+	}
+
+	return nil
+}
+
+// Captures screenshot of the element, viewport or the whole page.
+// Picture is downscaled to fit max size.
+func (c *WebCamera) capture(t *chrome.Tab) ([]byte, error) {
+	clip, err := c.getClip(t)
+	if err != nil {
+		return nil, errors.Wrap(err, "clip failed")
+	}
+
+	params := &captureParams{
+		Format: c.Settings.Format,
+	}
+
+	if formatPng != c.Settings.Format {
+		params.Quality = c.Settings.Quality
+	}
+
+	if nil == clip && c.Settings.FullPage {
+		clip, err = c.getPageClip(t)
+		if err != nil {
+			return nil, errors.Wrap(err, "page size failed")
+		}
+	}
+
+	if nil == clip && (c.Settings.MaxWidth > 0 || c.Settings.MaxHeight > 0) {
+		clip = &captureClip{
+			Width:  float64(c.Settings.Width),
+			Height: float64(c.Settings.Height),
+		}
+	}
+
+	if clip != nil {
+		clip.Scale = c.getDownscale(clip)
+		params.Clip = clip
+		params.CaptureBeyondViewport = c.isBeyondViewport(clip)
+	}
+
+	result := &captureResult{}
+	err = c.sendCommand(t, "Page.captureScreenshot", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "screenshot failed")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "corrupted image")
+	}
+
+	return data, nil
+}
+
+// Returns the whole document region.
+func (c *WebCamera) getPageClip(t *chrome.Tab) (*captureClip, error) {
+	value, err := c.evaluate(t, `(function(){var e=document.documentElement;`+
+		`return {width:Math.max(e.scrollWidth,e.clientWidth),height:Math.max(e.scrollHeight,e.clientHeight)};})()`,
+		chromeCallTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	size, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("unexpected page size")
+	}
+
+	clip := &captureClip{}
+	clip.Width, _ = size["width"].(float64)   // nolint: gosec
+	clip.Height, _ = size["height"].(float64) // nolint: gosec
+
+	if 0 == clip.Width || 0 == clip.Height {
+		return nil, errors.New("page is empty")
+	}
+
+	return clip, nil
+}
+
+// Checks whether clip doesn't fit into the viewport.
+func (c *WebCamera) isBeyondViewport(clip *captureClip) bool {
+	return clip.X+clip.Width > float64(c.Settings.Width) ||
+		clip.Y+clip.Height > float64(c.Settings.Height)
+}
+
+// Returns clip scale which fits the picture into max size.
+// Resulting picture size also depends on the device scale factor.
+func (c *WebCamera) getDownscale(clip *captureClip) float64 {
+	scale := 1.0
+	if c.Settings.MaxWidth > 0 {
+		scale = math.Min(scale, float64(c.Settings.MaxWidth)/(clip.Width*c.Settings.Scale))
+	}
+
+	if c.Settings.MaxHeight > 0 {
+		scale = math.Min(scale, float64(c.Settings.MaxHeight)/(clip.Height*c.Settings.Scale))
+	}
+
+	return scale
+}
+
+// Sends raw chrome command and decodes its result.
+func (c *WebCamera) sendCommand(t *chrome.Tab, method string, params interface{}, result interface{}) error {
+	select {
+	case response := <-t.Socket().SendCommand(socket.NewCommand(t.Socket(), method, params)):
+		if response.Error != nil && 0 != response.Error.Code {
+			return errors.New(response.Error.Message)
+		}
+
+		if nil == result {
+			return nil
+		}
+
+		return json.Unmarshal(response.Result, result)
+	case <-time.After(chromeCallTimeout):
+		return errors.New(method + " timeout")
+	}
+}
@@ -1,16 +1,69 @@
 package main
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Describes step which fills an input.
+	stepFill = "fill"
+	// Describes step which clicks an element.
+	stepClick = "click"
+	// Describes step which waits for an element.
+	stepWait = "wait"
+	// Describes step which evaluates JS.
+	stepEval = "eval"
+
+	// Describes PNG output format.
+	formatPng = "png"
+	// Describes portrait screen orientation.
+	orientationPortrait = "portrait"
+)
+
+// Step describes single action performed after page load.
+// Value could be provided either directly or as a secret.
+type Step struct {
+	Action      string `yaml:"action" validate:"oneof=fill click wait eval"`
+	Selector    string `yaml:"selector"`
+	Value       string `yaml:"value"`
+	ValueSecret string `yaml:"valueSecret"`
+	Script      string `yaml:"script"`
+	Timeout     int    `yaml:"timeout" validate:"gt=0" default:"5000"`
+}
+
+// Cookie describes cookie injected before page load.
+type Cookie struct {
+	Name        string `yaml:"name" validate:"required"`
+	Value       string `yaml:"value"`
+	ValueSecret string `yaml:"valueSecret"`
+	Domain      string `yaml:"domain"`
+	Path        string `yaml:"path" default:"/"`
+	Secure      bool   `yaml:"secure"`
+	HTTPOnly    bool   `yaml:"httpOnly"`
+}
 
 // Settings describes device settings.
 type Settings struct {
-	Address         string `yaml:"address" validate:"required"`
-	ChromeAddress   string `yaml:"chromeAddress" validate:"required"`
-	ChromePort      int    `yaml:"chromePort" validate:"required" default:"9222"`
-	PollingInterval int    `yaml:"pollingInterval" validate:"gt=10" default:"30"`
-	ReloadInterval  int    `yaml:"reloadInterval" default:"0"`
-	Width           int    `yaml:"width" default:"800"`
-	Height          int    `yaml:"height" default:"600"`
+	Address         string    `yaml:"address" validate:"required"`
+	ChromeAddress   string    `yaml:"chromeAddress" validate:"required"`
+	ChromePort      int       `yaml:"chromePort" validate:"required" default:"9222"`
+	PollingInterval int       `yaml:"pollingInterval" validate:"gt=10" default:"30"`
+	ReloadInterval  int       `yaml:"reloadInterval" default:"0"`
+	Width           int       `yaml:"width" default:"800"`
+	Height          int       `yaml:"height" default:"600"`
+	Selector        string    `yaml:"selector"`
+	Steps           []*Step   `yaml:"steps" validate:"dive"`
+	Cookies         []*Cookie `yaml:"cookies" validate:"dive"`
+	Format          string    `yaml:"format" validate:"oneof=jpeg png webp" default:"jpeg"`
+	Quality         int       `yaml:"quality" validate:"gte=1,lte=100" default:"100"`
+	Scale           float64   `yaml:"scale" validate:"gt=0" default:"1"`
+	Orientation     string    `yaml:"orientation" validate:"oneof=landscape portrait" default:"landscape"`
+	DarkMode        bool      `yaml:"darkMode"`
+	FullPage        bool      `yaml:"fullPage"`
+	MaxWidth        int       `yaml:"maxWidth" validate:"gte=0"`
+	MaxHeight       int       `yaml:"maxHeight" validate:"gte=0"`
 }
 
 // Validate performs settings validation.
@@ -19,5 +72,19 @@ func (s *Settings) Validate() error {
 		s.Address = "http://" + s.Address
 	}
 
+	for _, v := range s.Steps {
+		if stepEval == v.Action {
+			if "" == v.Script {
+				return errors.New("script is required for eval step")
+			}
+
+			continue
+		}
+
+		if "" == v.Selector {
+			return errors.New("selector is required for " + v.Action + " step")
+		}
+	}
+
 	return nil
 }
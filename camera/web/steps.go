@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	chrome "github.com/mkenney/go-chrome/tot"
+	"github.com/mkenney/go-chrome/tot/network"
+	"github.com/mkenney/go-chrome/tot/runtime"
+	"github.com/pkg/errors"
+	"go-home.io/x/server/plugins/common"
+)
+
+const (
+	// Describes how often wait step checks for an element.
+	waitPollInterval = 200 * time.Millisecond
+	// Describes timeout for a single chrome call.
+	chromeCallTimeout = 5 * time.Second
+)
+
+// Injects configured cookies into the tab.
+func (c *WebCamera) setCookies(t *chrome.Tab) error {
+	for _, v := range c.Settings.Cookies {
+		value, err := c.getValue(v.Value, v.ValueSecret)
+		if err != nil {
+			return errors.Wrap(err, "cookie value failed")
+		}
+
+		params := &network.SetCookieParams{
+			Name:     v.Name,
+			Value:    value,
+			Domain:   v.Domain,
+			Path:     v.Path,
+			Secure:   v.Secure,
+			HTTPOnly: v.HTTPOnly,
+		}
+
+		if "" == v.Domain {
+			params.URL = c.Settings.Address
+		}
+
+		select {
+		case result := <-t.Network().SetCookie(params):
+			if result.Err != nil {
+				return errors.Wrap(result.Err, "set cookie failed")
+			}
+
+			if !result.Success {
+				return errors.New("cookie " + v.Name + " was rejected")
+			}
+		case <-time.After(chromeCallTimeout):
+			return errors.New("set cookie timeout")
+		}
+	}
+
+	return nil
+}
+
+// Executes configured steps one by one.
+func (c *WebCamera) runSteps(t *chrome.Tab) error {
+	for i, v := range c.Settings.Steps {
+		err := c.runStep(t, v)
+		if err != nil {
+			c.Logger.Error("Failed to execute page step", err, common.LogURLToken, c.Settings.Address,
+				"step", v.Action, "index", strconv.Itoa(i))
+			return errors.Wrap(err, v.Action+" step failed")
+		}
+	}
+
+	return nil
+}
+
+// Executes a single step.
+func (c *WebCamera) runStep(t *chrome.Tab, step *Step) error {
+	timeout := time.Duration(step.Timeout) * time.Millisecond
+	selector := jsString(step.Selector)
+
+	switch step.Action {
+	case stepFill:
+		value, err := c.getValue(step.Value, step.ValueSecret)
+		if err != nil {
+			return errors.Wrap(err, "step value failed")
+		}
+
+		return c.expectTrue(t, `(function(){var e=document.querySelector(`+selector+`);if(!e){return false;}`+
+			`e.focus();e.value=`+jsString(value)+`;`+
+			`e.dispatchEvent(new Event('input',{bubbles:true}));`+
+			`e.dispatchEvent(new Event('change',{bubbles:true}));return true;})()`, timeout)
+	case stepClick:
+		return c.expectTrue(t, `(function(){var e=document.querySelector(`+selector+`);if(!e){return false;}`+
+			`e.click();return true;})()`, timeout)
+	case stepWait:
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			if nil == c.expectTrue(t, `document.querySelector(`+selector+`)!==null`, timeout) {
+				return nil
+			}
+
+			time.Sleep(waitPollInterval)
+		}
+
+		return errors.New("element " + step.Selector + " didn't appear")
+	case stepEval:
+		_, err := c.evaluate(t, step.Script, timeout)
+		return err
+	default:
+		return errors.New("unknown step action " + step.Action)
+	}
+}
+
+// Evaluates script which is expected to return true.
+func (c *WebCamera) expectTrue(t *chrome.Tab, script string, timeout time.Duration) error {
+	value, err := c.evaluate(t, script, timeout)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := value.(bool); !ok { // nolint: gosec
+		return errors.New("element not found")
+	}
+
+	return nil
+}
+
+// Evaluates JS in the tab and returns its result.
+// Promises are awaited.
+func (c *WebCamera) evaluate(t *chrome.Tab, script string, timeout time.Duration) (interface{}, error) {
+	select {
+	case result := <-t.Runtime().Evaluate(&runtime.EvaluateParams{
+		Expression:    script,
+		ReturnByValue: true,
+		AwaitPromise:  true,
+	}):
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		if result.ExceptionDetails != nil {
+			return nil, errors.New("script exception: " + result.ExceptionDetails.Text)
+		}
+
+		if nil == result.Result {
+			return nil, nil
+		}
+
+		return result.Result.Value, nil
+	case <-time.After(timeout):
+		return nil, errors.New("script timeout")
+	}
+}
+
+// Returns screenshot clip for the configured element.
+// Nil means the whole viewport.
+func (c *WebCamera) getClip(t *chrome.Tab) (*captureClip, error) {
+	if "" == c.Settings.Selector {
+		return nil, nil
+	}
+
+	value, err := c.evaluate(t, `(function(){var e=document.querySelector(`+jsString(c.Settings.Selector)+`);`+
+		`if(!e){return null;}var r=e.getBoundingClientRect();`+
+		`return {x:r.left+window.scrollX,y:r.top+window.scrollY,width:r.width,height:r.height};})()`,
+		chromeCallTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "element box failed")
+	}
+
+	box, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("element " + c.Settings.Selector + " not found")
+	}
+
+	clip := &captureClip{}
+	clip.X, _ = box["x"].(float64)           // nolint: gosec
+	clip.Y, _ = box["y"].(float64)           // nolint: gosec
+	clip.Width, _ = box["width"].(float64)   // nolint: gosec
+	clip.Height, _ = box["height"].(float64) // nolint: gosec
+
+	if 0 == clip.Width || 0 == clip.Height {
+		return nil, errors.New("element " + c.Settings.Selector + " is not visible")
+	}
+
+	return clip, nil
+}
+
+// Returns either value or secret.
+func (c *WebCamera) getValue(value string, secretName string) (string, error) {
+	if "" == secretName {
+		return value, nil
+	}
+
+	return c.secret.Get(secretName)
+}
+
+// Returns JS string literal.
+func jsString(value string) string {
+	data, _ := json.Marshal(value) // nolint: gosec
+	return string(data)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/base64"
 	"sync"
 	"time"
 
@@ -9,7 +8,6 @@ import (
 	"github.com/bdlm/log"
 	"github.com/mkenney/go-chrome/codes"
 	chrome "github.com/mkenney/go-chrome/tot"
-	"github.com/mkenney/go-chrome/tot/emulation"
 	"github.com/mkenney/go-chrome/tot/page"
 	"github.com/pkg/errors"
 	"go-home.io/x/server/plugins/common"
@@ -23,6 +21,7 @@ type WebCamera struct {
 	Settings *Settings
 	Logger   common.ILoggerProvider
 
+	secret         common.ISecretProvider
 	state          *device.CameraState
 	browser        *chrome.Chrome
 	tab            *chrome.Tab
@@ -34,6 +33,7 @@ type WebCamera struct {
 func (c *WebCamera) Init(data *device.InitDataDevice) error {
 	log.SetFormatter(&chromeLogger{Logger: data.Logger})
 	c.Logger = data.Logger
+	c.secret = data.Secret
 	c.state = &device.CameraState{}
 	c.stopChan = make(chan bool)
 	c.stopErrorsChan = make(chan bool)
@@ -126,11 +126,17 @@ func (c *WebCamera) reloadTab() {
 }
 
 // Opens desired tab.
+// Cookies are injected before navigation, steps are executed after page load.
 func (c *WebCamera) openTab() error {
 	c.Lock()
 	defer c.Unlock()
 
-	t, err := c.browser.NewTab(c.Settings.Address)
+	address := c.Settings.Address
+	if len(c.Settings.Cookies) > 0 {
+		address = "about:blank"
+	}
+
+	t, err := c.browser.NewTab(address)
 	if err != nil {
 		c.Logger.Error("Failed to open a new tab", err, common.LogURLToken, c.Settings.Address)
 		return errors.Wrap(err, "open tab failed")
@@ -138,40 +144,66 @@ func (c *WebCamera) openTab() error {
 	enableResult := <-t.Page().Enable()
 	if nil != enableResult.Err {
 		c.Logger.Error("Failed to enable chrome tab", enableResult.Err, common.LogURLToken, c.Settings.Address)
-		return errors.Wrap(err, "enable tab failed")
+		return errors.Wrap(enableResult.Err, "enable tab failed")
 	}
 
-	loadComplete := make(chan bool)
+	loadComplete := make(chan bool, 1)
 
 	t.Page().OnLoadEventFired(func(event *page.LoadEventFiredEvent) {
-		overrideResult := <-t.Emulation().SetDeviceMetricsOverride(
-			&emulation.SetDeviceMetricsOverrideParams{
-				Width:  c.Settings.Width,
-				Height: c.Settings.Height,
-				ScreenOrientation: &emulation.ScreenOrientation{
-					Type:  emulation.OrientationType.LandscapePrimary,
-					Angle: 90,
-				},
-			},
-		)
-		if nil != overrideResult.Err {
-			c.Logger.Error("Failed to setup chrome tab", enableResult.Err, common.LogURLToken, c.Settings.Address)
+		err := c.setupTab(t)
+		if err != nil {
+			c.Logger.Error("Failed to setup chrome tab", err, common.LogURLToken, c.Settings.Address)
 			return
 		}
 
-		loadComplete <- true
+		select {
+		case loadComplete <- true:
+		default:
+		}
 	})
 
+	if len(c.Settings.Cookies) > 0 {
+		err = c.navigateWithCookies(t)
+		if err != nil {
+			c.Logger.Error("Failed to load page with cookies", err, common.LogURLToken, c.Settings.Address)
+			t.Close() // nolint: gosec, errcheck
+			return errors.Wrap(err, "navigate failed")
+		}
+	}
+
 	select {
 	case <-loadComplete:
-		c.tab = t
-		go c.handleTabErrors()
-		return nil
-	case <-time.Tick(5 * time.Second):
+	case <-time.After(5 * time.Second):
 		err = errors.New("page load timeout")
 		c.Logger.Error("Failed to load page", err, common.LogURLToken, c.Settings.Address)
+		t.Close() // nolint: gosec, errcheck
+		return err
+	}
+
+	err = c.runSteps(t)
+	if err != nil {
+		t.Close() // nolint: gosec, errcheck
+		return errors.Wrap(err, "page steps failed")
+	}
+
+	c.tab = t
+	go c.handleTabErrors()
+	return nil
+}
+
+// Injects cookies and navigates blank tab to the page.
+func (c *WebCamera) navigateWithCookies(t *chrome.Tab) error {
+	err := c.setCookies(t)
+	if err != nil {
 		return err
 	}
+
+	select {
+	case result := <-t.Page().Navigate(&page.NavigateParams{URL: c.Settings.Address}):
+		return result.Err
+	case <-time.After(chromeCallTimeout):
+		return errors.New("navigate timeout")
+	}
 }
 
 // Handles errors occurred in a tab.
@@ -219,25 +251,12 @@ func (c *WebCamera) getPicture() error {
 	c.Lock()
 	defer c.Unlock()
 
-	select {
-	case screen := <-c.tab.Page().CaptureScreenshot(
-		&page.CaptureScreenshotParams{
-			Format:  page.Format.Jpeg,
-			Quality: 100,
-		}):
-		if screen.Err != nil {
-			c.Logger.Error("Failed to get page screenshot", screen.Err, common.LogURLToken, c.Settings.Address)
-			return screen.Err
-		}
-		data, err := base64.StdEncoding.DecodeString(screen.Data)
-		if err != nil {
-			c.Logger.Error("Received corrupted image", err, common.LogURLToken, c.Settings.Address)
-		}
-		c.state.Picture = string(data)
-		return nil
-	case <-time.Tick(5 * time.Second):
-		err := errors.New("timeout")
-		c.Logger.Error("Timeout while making screenshot", err, common.LogURLToken, c.Settings.Address)
-		return err
+	data, err := c.capture(c.tab)
+	if err != nil {
+		c.Logger.Error("Failed to get page screenshot", err, common.LogURLToken, c.Settings.Address)
+		return errors.Wrap(err, "capture failed")
 	}
+
+	c.state.Picture = string(data)
+	return nil
 }